@@ -0,0 +1,187 @@
+package pio
+
+import "sync"
+
+// instructionMemory is the instruction memory of a PIO block, written by the
+// allocator when loading programs. It is implemented by PIO.
+type instructionMemory interface {
+	writeInstructionMemory(offset uint8, value uint16)
+}
+
+// allocator tracks the instruction memory and state machine claims of a PIO
+// block. It holds no hardware state so its bookkeeping can be tested off-target.
+type allocator struct {
+	// mu and lockCores guard the fields below, see lock.
+	mu sync.Mutex
+	// Bitmask of used instruction space
+	usedSpaceMask uint32
+	// Length of the program loaded at each offset, 0 if none starts there.
+	programLengths [32]uint8
+	// Bitmask of claimed state machines.
+	claimedMask uint8
+}
+
+// lock guards the allocation and claim state. It excludes other goroutines with
+// a.mu and, on the RP2040, the other core with lockCores.
+func (a *allocator) lock() {
+	a.mu.Lock()
+	lockCores()
+}
+
+// unlock releases the locks taken by lock.
+func (a *allocator) unlock() {
+	unlockCores()
+	a.mu.Unlock()
+}
+
+// canAddProgramAtOffset reports whether instructions fit at offset. It does not lock.
+func (a *allocator) canAddProgramAtOffset(instructions []uint16, origin int8, offset uint8) bool {
+	// Non-relocatable programs must be added at offset
+	if origin >= 0 && origin != int8(offset) {
+		return false
+	}
+
+	// Program must not run past the end of instruction memory.
+	if int(offset)+len(instructions) > 32 {
+		return false
+	}
+
+	programMask := uint32((1 << len(instructions)) - 1)
+	return a.usedSpaceMask&(programMask<<offset) == 0
+}
+
+// findOffsetForProgram returns the offset instructions fit at, searching down
+// from the top of instruction memory, or -1 if there is no room. It does not lock.
+func (a *allocator) findOffsetForProgram(instructions []uint16, origin int8) int8 {
+	programLen := uint32(len(instructions))
+	programMask := uint32((1 << programLen) - 1)
+
+	// Program has fixed offset (not relocatable)
+	if origin >= 0 {
+		if uint32(origin) > 32-programLen {
+			return -1
+		}
+
+		if (a.usedSpaceMask & (programMask << origin)) != 0 {
+			return -1
+		}
+
+		return origin
+	}
+
+	// work down from the top always
+	for i := int8(32 - programLen); i >= 0; i-- {
+		if a.usedSpaceMask&(programMask<<uint32(i)) == 0 {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// addProgram loads instructions into mem wherever they fit and returns the
+// offset they were loaded at. It does not lock.
+func (a *allocator) addProgram(mem instructionMemory, instructions []uint16, origin int8) (uint8, error) {
+	offset := a.findOffsetForProgram(instructions, origin)
+	if offset < 0 {
+		return 0, a.programSpaceError(ErrOutOfProgramSpace, instructions, int(origin))
+	}
+	a.addProgramAtOffset(mem, instructions, origin, uint8(offset))
+	return uint8(offset), nil
+}
+
+// addProgramAtOffset loads instructions into mem at offset. It does not lock.
+func (a *allocator) addProgramAtOffset(mem instructionMemory, instructions []uint16, origin int8, offset uint8) error {
+	if !a.canAddProgramAtOffset(instructions, origin, offset) {
+		return a.programSpaceError(ErrNoSpaceAtOffset, instructions, int(offset))
+	}
+	writeProgram(mem, instructions, offset)
+	a.reserve(offset, len(instructions))
+	return nil
+}
+
+// replaceProgramAtOffset overwrites old, loaded at offset, with newProg in mem.
+// The reserved slots are unchanged. It does not lock.
+func (a *allocator) replaceProgramAtOffset(mem instructionMemory, old, newProg *Program, offset uint8) error {
+	if len(old.Instructions) != len(newProg.Instructions) {
+		return ErrProgramSizeMismatch
+	}
+	if newProg.Origin >= 0 && newProg.Origin != int8(offset) {
+		return ErrNoSpaceAtOffset
+	}
+	if !a.isLoadedAt(offset, len(old.Instructions)) {
+		return ErrProgramNotLoaded
+	}
+	writeProgram(mem, newProg.Instructions, offset)
+	return nil
+}
+
+// writeProgram writes instructions to mem starting at offset, relocating jump
+// instructions. It does not modify the used space mask.
+func writeProgram(mem instructionMemory, instructions []uint16, offset uint8) {
+	if int(offset)+len(instructions) > 32 {
+		panic("pio: program does not fit in instruction memory")
+	}
+	programLen := uint8(len(instructions))
+	for i := uint8(0); i < programLen; i++ {
+		instr := instructions[i]
+
+		// Patch jump instructions with relative offset
+		if INSTR_BITS_JMP == instr&INSTR_BITS_Msk {
+			mem.writeInstructionMemory(offset+i, instr+uint16(offset))
+		} else {
+			mem.writeInstructionMemory(offset+i, instr)
+		}
+	}
+}
+
+// reserve marks length slots starting at offset as used by a program.
+func (a *allocator) reserve(offset uint8, length int) {
+	programMask := uint32((1 << length) - 1)
+	a.usedSpaceMask |= programMask << uint32(offset)
+	a.programLengths[offset] = uint8(length)
+}
+
+// release frees the slots of the program loaded at offset. It returns false if
+// no program starts there.
+func (a *allocator) release(offset uint8) bool {
+	if offset > 31 || a.programLengths[offset] == 0 {
+		return false
+	}
+	programMask := uint32((1 << a.programLengths[offset]) - 1)
+	a.usedSpaceMask &^= programMask << uint32(offset)
+	a.programLengths[offset] = 0
+	return true
+}
+
+// isLoadedAt reports whether a program of length instructions was reserved at
+// offset, so it can be overwritten in place without touching its neighbours.
+func (a *allocator) isLoadedAt(offset uint8, length int) bool {
+	return offset <= 31 && length > 0 && int(a.programLengths[offset]) == length
+}
+
+// releasePrograms frees all instruction memory.
+func (a *allocator) releasePrograms() {
+	a.usedSpaceMask = 0
+	a.programLengths = [32]uint8{}
+}
+
+func (a *allocator) claimStateMachine(index uint8) bool {
+	if index > 3 {
+		panic("invalid state machine index")
+	}
+	if a.claimedMask&(1<<index) != 0 {
+		return false
+	}
+	a.claimedMask |= 1 << index
+	return true
+}
+
+func (a *allocator) programSpaceError(err error, instructions []uint16, offset int) error {
+	return &ProgramSpaceError{
+		Err:           err,
+		Length:        len(instructions),
+		Offset:        offset,
+		UsedSpaceMask: a.usedSpaceMask,
+	}
+}
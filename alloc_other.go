@@ -0,0 +1,8 @@
+//go:build !rp2040
+// +build !rp2040
+
+package pio
+
+// Off-target there is a single core and pio.mu is enough.
+func lockCores()   {}
+func unlockCores() {}
@@ -0,0 +1,197 @@
+package pio
+
+import (
+	"sync"
+	"testing"
+)
+
+// fakeInstructionMemory stands in for the instruction memory registers.
+type fakeInstructionMemory [32]uint16
+
+func (m *fakeInstructionMemory) writeInstructionMemory(offset uint8, value uint16) {
+	m[offset] = value
+}
+
+// addProgramLocked is PIO.AddProgram on a fake instruction memory.
+func (a *allocator) addProgramLocked(mem instructionMemory, instructions []uint16, origin int8) (uint8, bool) {
+	a.lock()
+	defer a.unlock()
+	offset, err := a.addProgram(mem, instructions, origin)
+	return offset, err == nil
+}
+
+func TestAllocatorConcurrentAddProgram(t *testing.T) {
+	var a allocator
+	var mem fakeInstructionMemory
+	program := []uint16{EncodeNOP(), EncodeNOP()}
+	const goroutines = 32
+	var wg sync.WaitGroup
+	offsets := make(chan uint8, goroutines)
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if offset, ok := a.addProgramLocked(&mem, program, -1); ok {
+				offsets <- offset
+			}
+		}()
+	}
+	wg.Wait()
+	close(offsets)
+	var used uint32
+	n := 0
+	for offset := range offsets {
+		mask := uint32(0b11) << offset
+		if used&mask != 0 {
+			t.Fatalf("program at offset %d overlaps another", offset)
+		}
+		used |= mask
+		n++
+	}
+	if n != 16 || used != 0xffffffff || a.usedSpaceMask != used {
+		t.Fatalf("got %d programs using %#x, mask %#x; want 16 filling memory", n, used, a.usedSpaceMask)
+	}
+}
+
+func TestAllocatorConcurrentAddRemove(t *testing.T) {
+	var a allocator
+	var mem fakeInstructionMemory
+	program := []uint16{EncodeNOP(), EncodeNOP(), EncodeNOP()}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				offset, ok := a.addProgramLocked(&mem, program, -1)
+				if !ok {
+					continue
+				}
+				a.lock()
+				released := a.release(offset)
+				a.unlock()
+				if !released {
+					t.Errorf("program at offset %d was not loaded", offset)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if a.usedSpaceMask != 0 || a.programLengths != [32]uint8{} {
+		t.Fatalf("got used mask %#x after removing all programs", a.usedSpaceMask)
+	}
+}
+
+func TestAllocatorConcurrentClaim(t *testing.T) {
+	var a allocator
+	var wg sync.WaitGroup
+	claims := make(chan uint8, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.lock()
+			defer a.unlock()
+			for sm := uint8(0); sm < 4; sm++ {
+				if a.claimStateMachine(sm) {
+					claims <- sm
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(claims)
+	var claimed uint8
+	for sm := range claims {
+		if claimed&(1<<sm) != 0 {
+			t.Fatalf("state machine %d claimed twice", sm)
+		}
+		claimed |= 1 << sm
+	}
+	if claimed != 0b1111 {
+		t.Fatalf("claimed %#b, want all four", claimed)
+	}
+}
+
+func TestAllocatorReplaceNextToAdjacentProgram(t *testing.T) {
+	var a allocator
+	var mem fakeInstructionMemory
+	firstProg := &Program{Instructions: []uint16{EncodePull(false, true), EncodeOut(SrcDestPins, 8), EncodeJmp(1)}, Origin: -1}
+	secondProg := &Program{Instructions: []uint16{EncodeNOP(), EncodeJmp(0)}, Origin: -1}
+	first, err := a.addProgram(&mem, firstProg.Instructions, firstProg.Origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := a.addProgram(&mem, secondProg.Instructions, secondProg.Origin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second+2 != first {
+		t.Fatalf("programs at %d and %d are not adjacent", second, first)
+	}
+	used := a.usedSpaceMask
+	lengths := a.programLengths
+	neighbour := mem[first : first+3]
+	want := append([]uint16(nil), neighbour...)
+
+	replacement := &Program{Instructions: []uint16{EncodeJmp(1), EncodeSet(SrcDestPins, 1)}, Origin: -1}
+	if err := a.replaceProgramAtOffset(&mem, secondProg, replacement, second); err != nil {
+		t.Fatal(err)
+	}
+	if got := mem[second]; got != EncodeJmp(1)+uint16(second) {
+		t.Errorf("replaced jump = %#04x, want it relocated to %d", got, second+1)
+	}
+	if got := mem[second+1]; got != EncodeSet(SrcDestPins, 1) {
+		t.Errorf("replaced instruction = %#04x, want %#04x", got, EncodeSet(SrcDestPins, 1))
+	}
+
+	// Replacements spanning or misaligned with loaded programs are rejected.
+	spanning := &Program{Instructions: make([]uint16, 5), Origin: -1}
+	for _, tt := range []struct {
+		old, new *Program
+		offset   uint8
+		err      error
+	}{
+		{spanning, spanning, second, ErrProgramNotLoaded},
+		{secondProg, replacement, second + 1, ErrProgramNotLoaded},
+		{secondProg, replacement, 0, ErrProgramNotLoaded},
+		{secondProg, firstProg, second, ErrProgramSizeMismatch},
+		{secondProg, &Program{Instructions: make([]uint16, 2), Origin: 0}, second, ErrNoSpaceAtOffset},
+	} {
+		if err := a.replaceProgramAtOffset(&mem, tt.old, tt.new, tt.offset); err != tt.err {
+			t.Errorf("replace %d instructions at %d: got %v, want %v", len(tt.new.Instructions), tt.offset, err, tt.err)
+		}
+	}
+	for i, v := range want {
+		if neighbour[i] != v {
+			t.Errorf("adjacent program slot %d = %#04x, want %#04x", int(first)+i, neighbour[i], v)
+		}
+	}
+	if a.usedSpaceMask != used || a.programLengths != lengths {
+		t.Errorf("replacement changed used mask to %#x", a.usedSpaceMask)
+	}
+}
+
+func TestAllocatorOffsetBounds(t *testing.T) {
+	var a allocator
+	for length := 1; length <= 32; length++ {
+		program := make([]uint16, length)
+		for offset := 0; offset < 40; offset++ {
+			fits := offset+length <= 32
+			if got := a.canAddProgramAtOffset(program, -1, uint8(offset)); got != fits {
+				t.Errorf("canAddProgramAtOffset(len %d, offset %d) = %v, want %v", length, offset, got, fits)
+			}
+			if offset < 32 {
+				got := a.findOffsetForProgram(program, int8(offset))
+				if fits != (got == int8(offset)) || !fits && got != -1 {
+					t.Errorf("findOffsetForProgram(len %d, origin %d) = %d", length, offset, got)
+				}
+			}
+		}
+		if got := a.findOffsetForProgram(program, -1); int(got)+length > 32 {
+			t.Errorf("findOffsetForProgram(len %d) = %d runs past instruction memory", length, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package pio
+
+// Program blobs are a compact binary encoding of a Program, so programs can be
+// embedded with //go:embed or received over a link and loaded at runtime
+// without recompiling. The format is a 6 byte header followed by the
+// instructions:
+//
+//	offset  size  field
+//	0       1     instruction count N, 1..32
+//	1       1     origin as int8, -1 if relocatable
+//	2       1     wrap target
+//	3       1     wrap
+//	4       1     side-set count, not including the enable bit
+//	5       1     flags: bit 0 side-set optional, bit 1 side-set pindirs
+//	6       2N    instructions as little endian uint16
+const (
+	blobHeaderLen      = 6
+	blobFlagSideSetOpt = 1 << 0
+	blobFlagSideSetDir = 1 << 1
+)
+
+// LoadProgramBlob parses a program blob as produced by MarshalBlob.
+// It returns ErrInvalidBlob if the blob is malformed or its lengths do not agree.
+func LoadProgramBlob(data []byte) (*Program, error) {
+	if len(data) < blobHeaderLen {
+		return nil, ErrInvalidBlob
+	}
+	n := int(data[0])
+	origin := int8(data[1])
+	flags := data[5]
+	if len(data) != blobHeaderLen+2*n ||
+		!validBlobProgram(n, origin, data[2], data[3], data[4]) ||
+		flags&^(blobFlagSideSetOpt|blobFlagSideSetDir) != 0 {
+		return nil, ErrInvalidBlob
+	}
+	p := &Program{
+		Instructions:   make([]uint16, n),
+		Origin:         origin,
+		WrapTarget:     data[2],
+		Wrap:           data[3],
+		SideSetCount:   data[4],
+		SideSetOpt:     flags&blobFlagSideSetOpt != 0,
+		SideSetPindirs: flags&blobFlagSideSetDir != 0,
+	}
+	for i := range p.Instructions {
+		p.Instructions[i] = uint16(data[blobHeaderLen+2*i]) | uint16(data[blobHeaderLen+2*i+1])<<8
+	}
+	return p, nil
+}
+
+// MarshalBlob encodes the program in the format read by LoadProgramBlob.
+// It returns ErrInvalidBlob if LoadProgramBlob would reject the result, e.g.
+// for an empty program, more than 32 instructions or an origin outside -1..31.
+func (p *Program) MarshalBlob() ([]byte, error) {
+	if !validBlobProgram(len(p.Instructions), p.Origin, p.WrapTarget, p.Wrap, p.SideSetCount) {
+		return nil, ErrInvalidBlob
+	}
+	var flags byte
+	if p.SideSetOpt {
+		flags |= blobFlagSideSetOpt
+	}
+	if p.SideSetPindirs {
+		flags |= blobFlagSideSetDir
+	}
+	data := make([]byte, blobHeaderLen, blobHeaderLen+2*len(p.Instructions))
+	data[0] = byte(len(p.Instructions))
+	data[1] = byte(p.Origin)
+	data[2] = p.WrapTarget
+	data[3] = p.Wrap
+	data[4] = p.SideSetCount
+	data[5] = flags
+	for _, instr := range p.Instructions {
+		data = append(data, byte(instr), byte(instr>>8))
+	}
+	return data, nil
+}
+
+// validBlobProgram reports whether a program of n instructions with the given
+// header fields can be encoded as a blob and loaded into instruction memory.
+func validBlobProgram(n int, origin int8, wrapTarget, wrap, sideSetCount uint8) bool {
+	return n > 0 && n <= 32 &&
+		origin >= -1 && int(origin)+n <= 32 &&
+		int(wrapTarget) < n && int(wrap) < n &&
+		sideSetCount <= 5
+}
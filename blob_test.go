@@ -0,0 +1,87 @@
+package pio
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBlobRoundTrip(t *testing.T) {
+	programs := []*Program{
+		{Instructions: []uint16{EncodeMov(SrcDestY, SrcDestY)}, Origin: -1},
+		{
+			Instructions:   []uint16{0x80a0, 0xa027, 0x0042, 0xff00, 0xc100},
+			Origin:         27,
+			WrapTarget:     1,
+			Wrap:           4,
+			SideSetCount:   2,
+			SideSetOpt:     true,
+			SideSetPindirs: true,
+		},
+		{Instructions: make([]uint16, 32), Origin: 0, Wrap: 31, SideSetCount: 5},
+	}
+	for _, p := range programs {
+		data, err := p.MarshalBlob()
+		if err != nil {
+			t.Fatalf("MarshalBlob(%+v): %v", p, err)
+		}
+		got, err := LoadProgramBlob(data)
+		if err != nil {
+			t.Fatalf("LoadProgramBlob(% x): %v", data, err)
+		}
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("round trip = %+v, want %+v", got, p)
+		}
+		again, _ := got.MarshalBlob()
+		if !bytes.Equal(again, data) {
+			t.Errorf("re-marshal = % x, want % x", again, data)
+		}
+	}
+}
+
+func TestMarshalBlobInvalid(t *testing.T) {
+	nop := EncodeMov(SrcDestY, SrcDestY)
+	programs := []*Program{
+		{Origin: -1}, // Empty.
+		{Instructions: make([]uint16, 33), Origin: -1}, // Too large.
+		{Instructions: []uint16{nop}, Origin: -2},
+		{Instructions: []uint16{nop}, Origin: 32},
+		{Instructions: []uint16{nop, nop}, Origin: 31}, // Runs past the end of memory.
+		{Instructions: []uint16{nop}, Origin: -1, Wrap: 1},
+		{Instructions: []uint16{nop}, Origin: -1, WrapTarget: 1},
+		{Instructions: []uint16{nop}, Origin: -1, SideSetCount: 6},
+	}
+	for _, p := range programs {
+		data, err := p.MarshalBlob()
+		if !errors.Is(err, ErrInvalidBlob) {
+			t.Errorf("MarshalBlob(%+v) = % x, %v; want ErrInvalidBlob", p, data, err)
+		}
+	}
+}
+
+func TestLoadProgramBlobInvalid(t *testing.T) {
+	valid, err := (&Program{Instructions: []uint16{0xa042, 0x0000}, Origin: -1, Wrap: 1}).MarshalBlob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), valid...))
+	}
+	blobs := [][]byte{
+		nil,
+		valid[:blobHeaderLen-1],
+		valid[:len(valid)-1],
+		corrupt(func(b []byte) []byte { return append(b, 0, 0) }),
+		corrupt(func(b []byte) []byte { b[0] = 0; return b[:blobHeaderLen] }),
+		corrupt(func(b []byte) []byte { b[1] = 31; return b }),
+		corrupt(func(b []byte) []byte { b[3] = 2; return b }),
+		corrupt(func(b []byte) []byte { b[4] = 6; return b }),
+		corrupt(func(b []byte) []byte { b[5] = 1 << 2; return b }),
+	}
+	for _, data := range blobs {
+		if p, err := LoadProgramBlob(data); !errors.Is(err, ErrInvalidBlob) {
+			t.Errorf("LoadProgramBlob(% x) = %+v, %v; want ErrInvalidBlob", data, p, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"device/rp"
+	"runtime/interrupt"
+)
+
+// callbackIRQLine is the PIO system interrupt line used to dispatch
+// callbacks. Line 0 is left free for user installed handlers.
+const callbackIRQLine = 1
+
+// interruptCallbacks holds the callbacks indexed by PIO block, interrupt kind
+// and state machine index.
+var interruptCallbacks [2][2][4]func()
+
+// OnRxNotEmpty registers cb to be called from interrupt context while the
+// state machine's RX FIFO is not empty. cb must read from the FIFO (or
+// unregister itself) or it will be called again as soon as it returns.
+// Passing nil unregisters the callback and disables the interrupt source.
+//
+// Callbacks are dispatched from the PIO block's IRQ_1 system interrupt
+// (rp.IRQ_PIO0_IRQ_1 or rp.IRQ_PIO1_IRQ_1) which this package installs, so
+// users must not install their own handler for it.
+func (sm StateMachine) OnRxNotEmpty(cb func()) {
+	sm.setCallback(InterruptRxNotEmpty, cb)
+}
+
+// OnTxNotFull registers cb to be called from interrupt context while the
+// state machine's TX FIFO is not full. cb must write to the FIFO (or unregister
+// itself once there is no more data) or it will be called again as soon as it
+// returns. See OnRxNotEmpty.
+func (sm StateMachine) OnTxNotFull(cb func()) {
+	sm.setCallback(InterruptTxNotFull, cb)
+}
+
+func (sm StateMachine) setCallback(kind InterruptKind, cb func()) {
+	block := sm.PIO.BlockIndex()
+	state := interrupt.Disable()
+	interruptCallbacks[block][kind][sm.index] = cb
+	interrupt.Restore(state)
+	if cb != nil {
+		enableCallbackInterrupt(block)
+	}
+	sm.SetInterruptEnabled(callbackIRQLine, kind, cb != nil)
+}
+
+// enableCallbackInterrupt installs and enables the callback dispatch
+// handler for a PIO block.
+func enableCallbackInterrupt(block uint8) {
+	switch block {
+	case 0:
+		interrupt.New(rp.IRQ_PIO0_IRQ_1, handlePIO0Callbacks).Enable()
+	case 1:
+		interrupt.New(rp.IRQ_PIO1_IRQ_1, handlePIO1Callbacks).Enable()
+	}
+}
+
+func handlePIO0Callbacks(interrupt.Interrupt) { dispatchCallbacks(PIO0, 0) }
+func handlePIO1Callbacks(interrupt.Interrupt) { dispatchCallbacks(PIO1, 1) }
+
+// dispatchCallbacks calls the callback of every asserted interrupt source of
+// the block's callback line. INTS has one group of four bits (one per
+// state machine) for each InterruptKind.
+func dispatchCallbacks(pio *PIO, block uint8) {
+	ints := pio.InterruptStatus(callbackIRQLine)
+	for {
+		smIndex, kind, ok := DecodeInterruptSource(ints)
+		if !ok {
+			break
+		}
+		ints &= ints - 1
+		if kind > InterruptTxNotFull {
+			continue
+		}
+		if cb := interruptCallbacks[block][kind][smIndex]; cb != nil {
+			cb()
+		}
+	}
+}
@@ -0,0 +1,107 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"device/rp"
+	"runtime/interrupt"
+	"runtime/volatile"
+	"unsafe"
+)
+
+// ClaimSpinlock is the RP2040 hardware spinlock (0..31) used by the claim and
+// program allocation methods to coordinate both cores. It defaults to 11, the spinlock the
+// c-sdk reserves for hardware claiming. It must be changed before any claim if
+// another library uses that spinlock.
+var ClaimSpinlock uint8 = 11
+
+// ClaimStateMachine marks the state machine at index as used so that other
+// drivers calling ClaimUnusedStateMachine do not take it. It returns false if
+// the state machine was already claimed.
+func (pio *PIO) ClaimStateMachine(index uint8) bool {
+	pio.lock()
+	defer pio.unlock()
+	return pio.claimStateMachine(index)
+}
+
+// UnclaimStateMachine releases a claimed state machine.
+func (pio *PIO) UnclaimStateMachine(index uint8) {
+	pio.lock()
+	defer pio.unlock()
+	pio.claimedMask &^= 1 << index
+}
+
+// ClaimUnusedStateMachine claims and returns the first unclaimed state machine.
+func (pio *PIO) ClaimUnusedStateMachine() (StateMachine, error) {
+	pio.lock()
+	defer pio.unlock()
+	for i := uint8(0); i < 4; i++ {
+		if pio.claimStateMachine(i) {
+			return pio.StateMachine(i), nil
+		}
+	}
+	return StateMachine{}, ErrNoFreeStateMachine
+}
+
+// ClaimUnusedStateMachinePair claims two unclaimed state machines of the same
+// PIO block at once, for protocols where two state machines cooperate, e.g.
+// synchronized through IRQ flags. Nothing is claimed if fewer than two are free,
+// so another driver cannot grab the second between two ClaimUnusedStateMachine calls.
+func (pio *PIO) ClaimUnusedStateMachinePair() (a, b StateMachine, ok bool) {
+	pio.lock()
+	defer pio.unlock()
+	var free [2]uint8
+	n := 0
+	for i := uint8(0); i < 4 && n < 2; i++ {
+		if pio.claimedMask&(1<<i) == 0 {
+			free[n] = i
+			n++
+		}
+	}
+	if n < 2 {
+		return StateMachine{}, StateMachine{}, false
+	}
+	pio.claimStateMachine(free[0])
+	pio.claimStateMachine(free[1])
+	return pio.StateMachine(free[0]), pio.StateMachine(free[1]), true
+}
+
+// ClaimStateMachineMultiCore is like ClaimStateMachine.
+//
+// Deprecated: all claim and program allocation methods now take hardware
+// spinlock ClaimSpinlock and are safe to use from both cores; use ClaimStateMachine.
+func (pio *PIO) ClaimStateMachineMultiCore(index uint8) bool {
+	return pio.ClaimStateMachine(index)
+}
+
+// claimIRQState holds the interrupt state saved by lockCores. It is only accessed
+// while holding spinlock ClaimSpinlock, which is shared by both PIO blocks.
+var claimIRQState interrupt.State
+
+// lockCores excludes the other core from the program allocation and claim
+// state of both PIO blocks by taking hardware spinlock ClaimSpinlock, with
+// interrupts disabled so the critical section is short. See allocator.lock.
+func lockCores() {
+	state := interrupt.Disable()
+	// Reading a spinlock register returns non-zero when the lock was acquired.
+	for spinlock(ClaimSpinlock).Get() == 0 {
+	}
+	claimIRQState = state
+}
+
+// unlockCores releases the spinlock taken by lockCores.
+func unlockCores() {
+	state := claimIRQState
+	spinlock(ClaimSpinlock).Set(0) // Any write releases the lock.
+	interrupt.Restore(state)
+}
+
+// spinlock returns the SIO hardware spinlock register at index.
+func spinlock(index uint8) *volatile.Register32 {
+	if index > 31 {
+		panic("invalid spinlock index")
+	}
+	start := unsafe.Pointer(&rp.SIO.SPINLOCK0)
+	return (*volatile.Register32)(unsafe.Pointer(uintptr(start) + uintptr(index)*4))
+}
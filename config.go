@@ -1,10 +1,5 @@
 package pio
 
-import (
-	"device/rp"
-	"machine"
-)
-
 // DefaultStateMachineConfig returns the default configuration
 // for a PIO state machine.
 //
@@ -17,8 +12,8 @@ func DefaultStateMachineConfig() StateMachineConfig {
 	cfg := StateMachineConfig{}
 	cfg.SetClkDivIntFrac(1, 0)
 	cfg.SetWrap(0, 31)
-	cfg.SetInShift(true, false, 32)
-	cfg.SetOutShift(true, false, 32)
+	cfg.SetInShift(true, false, ShiftThresholdFull)
+	cfg.SetOutShift(true, false, ShiftThresholdFull)
 	return cfg
 }
 
@@ -37,8 +32,37 @@ type StateMachineConfig struct {
 // SetClkDivIntFrac sets the clock divider for the state
 // machine from a whole and fractional part.
 func (cfg *StateMachineConfig) SetClkDivIntFrac(div uint16, frac uint8) {
-	cfg.ClkDiv = (uint32(frac) << rp.PIO0_SM0_CLKDIV_FRAC_Pos) |
-		(uint32(div) << rp.PIO0_SM0_CLKDIV_INT_Pos)
+	cfg.ClkDiv = (uint32(frac) << pio0_SM0_CLKDIV_FRAC_Pos) |
+		(uint32(div) << pio0_SM0_CLKDIV_INT_Pos)
+}
+
+// clkDivForFreq returns the 16.8 fixed point clock divider that makes a state
+// machine run at targetHz given the system clock sysClkHz. The result
+// is clamped to the valid divider range of 1.0 to 65535+255/256.
+func clkDivForFreq(sysClkHz, targetHz uint32) (whole uint16, frac uint8) {
+	if targetHz == 0 {
+		return 0xffff, 0xff
+	}
+	div := uint64(sysClkHz) * 256 / uint64(targetHz)
+	switch {
+	case div < 256:
+		div = 256
+	case div > 0xffffff:
+		div = 0xffffff
+	}
+	return uint16(div >> 8), uint8(div)
+}
+
+// SetBaud sets the clock divider so that a serial program spending
+// cyclesPerBit state machine cycles on each bit runs at baud bits per second,
+// given the system clock frequency sysClkHz. For example the c-sdk UART TX
+// program uses 8 cycles per bit. The divider is clamped to its valid range.
+func (cfg *StateMachineConfig) SetBaud(sysClkHz, baud uint32, cyclesPerBit uint8) {
+	targetHz := uint64(baud) * uint64(cyclesPerBit)
+	if targetHz > uint64(sysClkHz) {
+		targetHz = uint64(sysClkHz) // Divider clamps to 1.0.
+	}
+	cfg.SetClkDivIntFrac(clkDivForFreq(sysClkHz, uint32(targetHz)))
 }
 
 // SetWrap sets the wrapping configuration for the state machine
@@ -47,51 +71,157 @@ func (cfg *StateMachineConfig) SetClkDivIntFrac(div uint16, frac uint8) {
 // c-sdk - any changes should be backwards compatible.
 func (cfg *StateMachineConfig) SetWrap(wrapTarget uint8, wrap uint8) {
 	cfg.ExecCtrl =
-		(cfg.ExecCtrl & ^uint32(rp.PIO0_SM0_EXECCTRL_WRAP_TOP_Msk|rp.PIO0_SM0_EXECCTRL_WRAP_BOTTOM_Msk)) |
-			(uint32(wrapTarget) << rp.PIO0_SM0_EXECCTRL_WRAP_BOTTOM_Pos) |
-			(uint32(wrap) << rp.PIO0_SM0_EXECCTRL_WRAP_TOP_Pos)
+		(cfg.ExecCtrl & ^uint32(pio0_SM0_EXECCTRL_WRAP_TOP_Msk|pio0_SM0_EXECCTRL_WRAP_BOTTOM_Msk)) |
+			(uint32(wrapTarget) << pio0_SM0_EXECCTRL_WRAP_BOTTOM_Pos) |
+			(uint32(wrap) << pio0_SM0_EXECCTRL_WRAP_TOP_Pos)
 }
 
+// GetWrap returns the wrap target and wrap top set with SetWrap.
+func (cfg *StateMachineConfig) GetWrap() (target, top uint8) {
+	target = uint8((cfg.ExecCtrl & pio0_SM0_EXECCTRL_WRAP_BOTTOM_Msk) >> pio0_SM0_EXECCTRL_WRAP_BOTTOM_Pos)
+	top = uint8((cfg.ExecCtrl & pio0_SM0_EXECCTRL_WRAP_TOP_Msk) >> pio0_SM0_EXECCTRL_WRAP_TOP_Pos)
+	return target, top
+}
+
+// ShiftThresholdFull is the autopush/autopull threshold that shifts the full
+// 32 bits of the ISR or OSR. The hardware encodes it as 0 in SHIFTCTRL, which
+// SetInShift and SetOutShift do for it.
+const ShiftThresholdFull = 32
+
 // SetInShift sets the 'in' shifting parameters in a state machine configuration
+//
+// pushThreshold is the number of bits (1..32) shifted in before an autopush,
+// use ShiftThresholdFull for 32. Larger values are clamped to 32.
 func (cfg *StateMachineConfig) SetInShift(shiftRight bool, autoPush bool, pushThreshold uint16) {
 	cfg.ShiftCtrl = cfg.ShiftCtrl &
-		^uint32(rp.PIO0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk|
-			rp.PIO0_SM0_SHIFTCTRL_AUTOPUSH_Msk|
-			rp.PIO0_SM0_SHIFTCTRL_PUSH_THRESH_Msk) |
-		(boolToBit(shiftRight) << rp.PIO0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos) |
-		(boolToBit(autoPush) << rp.PIO0_SM0_SHIFTCTRL_AUTOPUSH_Pos) |
-		(uint32(pushThreshold&0x1f) << rp.PIO0_SM0_SHIFTCTRL_PUSH_THRESH_Pos)
+		^uint32(pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk|
+			pio0_SM0_SHIFTCTRL_AUTOPUSH_Msk|
+			pio0_SM0_SHIFTCTRL_PUSH_THRESH_Msk) |
+		(boolToBit(shiftRight) << pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos) |
+		(boolToBit(autoPush) << pio0_SM0_SHIFTCTRL_AUTOPUSH_Pos) |
+		(shiftThresholdBits(pushThreshold) << pio0_SM0_SHIFTCTRL_PUSH_THRESH_Pos)
 }
 
 // SetOutShift sets the 'out' shifting parameters in a state machine configuration
+//
+// pushThreshold is the number of bits (1..32) shifted out before an autopull,
+// use ShiftThresholdFull for 32. Larger values are clamped to 32.
 func (cfg *StateMachineConfig) SetOutShift(shiftRight bool, autoPush bool, pushThreshold uint16) {
 	cfg.ShiftCtrl = cfg.ShiftCtrl &
-		^uint32(rp.PIO0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk|
-			rp.PIO0_SM0_SHIFTCTRL_AUTOPULL_Msk|
-			rp.PIO0_SM0_SHIFTCTRL_PULL_THRESH_Msk) |
-		(boolToBit(shiftRight) << rp.PIO0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos) |
-		(boolToBit(autoPush) << rp.PIO0_SM0_SHIFTCTRL_AUTOPULL_Pos) |
-		(uint32(pushThreshold&0x1f) << rp.PIO0_SM0_SHIFTCTRL_PULL_THRESH_Pos)
+		^uint32(pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk|
+			pio0_SM0_SHIFTCTRL_AUTOPULL_Msk|
+			pio0_SM0_SHIFTCTRL_PULL_THRESH_Msk) |
+		(boolToBit(shiftRight) << pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos) |
+		(boolToBit(autoPush) << pio0_SM0_SHIFTCTRL_AUTOPULL_Pos) |
+		(shiftThresholdBits(pushThreshold) << pio0_SM0_SHIFTCTRL_PULL_THRESH_Pos)
+}
+
+// shiftThresholdBits encodes a shift threshold for the 5-bit SHIFTCTRL field,
+// where 0 means 32. Thresholds above 32 are clamped rather than wrapped.
+func shiftThresholdBits(threshold uint16) uint32 {
+	if threshold > ShiftThresholdFull {
+		threshold = ShiftThresholdFull
+	}
+	return uint32(threshold) & 0x1f
+}
+
+// SetInEndian sets only the 'in' shift direction, leaving autopush and the
+// push threshold untouched. msbFirst shifts data in to the left so that the
+// first bit read ends up most significant, which corresponds to SetInShift with shiftRight=false.
+func (cfg *StateMachineConfig) SetInEndian(msbFirst bool) {
+	cfg.ShiftCtrl = (cfg.ShiftCtrl & ^uint32(pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk)) |
+		(boolToBit(!msbFirst) << pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos)
+}
+
+// SetOutEndian sets only the 'out' shift direction, leaving autopull and the
+// pull threshold untouched. msbFirst shifts data out to the left so the most
+// significant bit is output first, which corresponds to SetOutShift with shiftRight=false.
+func (cfg *StateMachineConfig) SetOutEndian(msbFirst bool) {
+	cfg.ShiftCtrl = (cfg.ShiftCtrl & ^uint32(pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk)) |
+		(boolToBit(!msbFirst) << pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos)
+}
+
+// SetByteInput enables autopush every 8 bits. msbFirst shifts data in to the
+// left so the first bit read is the most significant of the byte. A byte
+// shifted in to the left sits in the 8 least significant bits of the pushed
+// word; shifted in to the right it sits in the 8 most significant bits.
+func (cfg *StateMachineConfig) SetByteInput(msbFirst bool) {
+	cfg.SetInShift(!msbFirst, true, 8)
+}
+
+// SetHalfwordInput enables autopush every 16 bits. See SetByteInput.
+func (cfg *StateMachineConfig) SetHalfwordInput(msbFirst bool) {
+	cfg.SetInShift(!msbFirst, true, 16)
+}
+
+// SetWordInput enables autopush every 32 bits. See SetByteInput.
+func (cfg *StateMachineConfig) SetWordInput(msbFirst bool) {
+	cfg.SetInShift(!msbFirst, true, ShiftThresholdFull)
+}
+
+// SetByteOutput enables autopull every 8 bits. msbFirst shifts data out to the
+// left so the most significant bit of the byte is output first; the byte is then
+// taken from the 8 most significant bits of each word, otherwise from the 8 least
+// significant bits. 8-bit DMA writes to the TX FIFO replicate the byte across the
+// word, so either direction works with them.
+func (cfg *StateMachineConfig) SetByteOutput(msbFirst bool) {
+	cfg.SetOutShift(!msbFirst, true, 8)
+}
+
+// SetHalfwordOutput enables autopull every 16 bits. See SetByteOutput.
+func (cfg *StateMachineConfig) SetHalfwordOutput(msbFirst bool) {
+	cfg.SetOutShift(!msbFirst, true, 16)
+}
+
+// SetWordOutput enables autopull every 32 bits. See SetByteOutput.
+func (cfg *StateMachineConfig) SetWordOutput(msbFirst bool) {
+	cfg.SetOutShift(!msbFirst, true, ShiftThresholdFull)
 }
 
 // SetSideSet sets the sideset parameters in a state machine configuration
 //
+// bitCount is the number of side-set bits including the enable bit when
+// optional is true. If pindirs is true side-set values drive the pin
+// directions instead of the pin levels, as used by open-drain buses such as I2C.
+//
 // This function is used by code generated by pioasm, in the RP2040
 // c-sdk - any changes should be backwards compatible.
 func (cfg *StateMachineConfig) SetSideSet(bitCount uint8, optional bool, pindirs bool) {
-	cfg.PinCtrl = (cfg.PinCtrl & ^uint32(rp.PIO0_SM0_PINCTRL_SIDESET_COUNT_Msk)) |
-		(uint32(bitCount) << uint32(rp.PIO0_SM0_PINCTRL_SIDESET_COUNT_Pos))
+	cfg.PinCtrl = (cfg.PinCtrl & ^uint32(pio0_SM0_PINCTRL_SIDESET_COUNT_Msk)) |
+		(uint32(bitCount) << uint32(pio0_SM0_PINCTRL_SIDESET_COUNT_Pos))
+
+	cfg.ExecCtrl = (cfg.ExecCtrl & ^uint32(pio0_SM0_EXECCTRL_SIDE_EN_Msk|pio0_SM0_EXECCTRL_SIDE_PINDIR_Msk)) |
+		(boolToBit(optional) << pio0_SM0_EXECCTRL_SIDE_EN_Pos) |
+		(boolToBit(pindirs) << pio0_SM0_EXECCTRL_SIDE_PINDIR_Pos)
+}
 
-	cfg.ExecCtrl = (cfg.ExecCtrl & ^uint32(rp.PIO0_SM0_EXECCTRL_SIDE_EN_Msk|rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR_Msk)) |
-		(boolToBit(optional) << rp.PIO0_SM0_EXECCTRL_SIDE_EN_Pos) |
-		(boolToBit(pindirs) << rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR_Pos)
+// GetSideSet returns the side-set parameters set with SetSideSet. As there,
+// count includes the enable bit when optional is true.
+func (cfg *StateMachineConfig) GetSideSet() (count uint8, optional, pindirs bool) {
+	count = uint8((cfg.PinCtrl & pio0_SM0_PINCTRL_SIDESET_COUNT_Msk) >> pio0_SM0_PINCTRL_SIDESET_COUNT_Pos)
+	optional = cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_EN != 0
+	pindirs = cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_PINDIR != 0
+	return count, optional, pindirs
 }
 
-// SetSetPins sets the pins a PIO 'set' instruction modifies
-func (cfg *StateMachineConfig) SetSetPins(base machine.Pin, count uint8) {
-	cfg.PinCtrl = (cfg.PinCtrl & ^uint32(rp.PIO0_SM0_PINCTRL_SET_BASE_Msk|rp.PIO0_SM0_PINCTRL_SET_COUNT_Msk)) |
-		(uint32(base) << rp.PIO0_SM0_PINCTRL_SET_BASE_Pos) |
-		(uint32(count) << rp.PIO0_SM0_PINCTRL_SET_COUNT_Pos)
+// ClearSideSet disables side-set: the side-set count is set to 0 and the
+// optional and pindirs bits are cleared, so all 5 bits of the delay/side-set
+// field are delay bits again. Use it when reusing a configuration for a program
+// without side-set, since leftover side-set bits would corrupt its delays.
+func (cfg *StateMachineConfig) ClearSideSet() {
+	cfg.SetSideSet(0, false, false)
+}
+
+// DelaySideSetBudget returns how the 5-bit delay/side-set field of every
+// instruction is split under this configuration. Side-set bits, including the
+// enable bit of an optional side-set, are taken from the most significant end
+// leaving the rest for delay cycles; with 2 side-set bits delays are limited to 7 cycles.
+func (cfg *StateMachineConfig) DelaySideSetBudget() (delayBits, sideSetBits uint8) {
+	sideSetBits = uint8((cfg.PinCtrl & pio0_SM0_PINCTRL_SIDESET_COUNT_Msk) >> pio0_SM0_PINCTRL_SIDESET_COUNT_Pos)
+	if sideSetBits > 5 {
+		sideSetBits = 5
+	}
+	return 5 - sideSetBits, sideSetBits
 }
 
 type FifoJoin int
@@ -110,6 +240,120 @@ const (
 	}
 */
 func (cfg *StateMachineConfig) SetFIFOJoin(join FifoJoin) {
-	cfg.ShiftCtrl = (cfg.ShiftCtrl & ^uint32(rp.PIO0_SM0_SHIFTCTRL_FJOIN_TX_Msk|rp.PIO0_SM0_SHIFTCTRL_FJOIN_RX_Msk)) |
-		(uint32(join) << rp.PIO0_SM0_SHIFTCTRL_FJOIN_TX_Pos)
+	cfg.ShiftCtrl = (cfg.ShiftCtrl & ^uint32(pio0_SM0_SHIFTCTRL_FJOIN_TX_Msk|pio0_SM0_SHIFTCTRL_FJOIN_RX_Msk)) |
+		(uint32(join) << pio0_SM0_SHIFTCTRL_FJOIN_TX_Pos)
+}
+
+// SetOutSpecial sets the special 'out' operations in a state machine configuration.
+//
+// sticky makes the state machine continuously assert its most recent OUT/SET
+// to the pins. If hasEnablePin is true the data bit at enablePinIndex of an
+// 'out' instruction decides whether the write to the pins takes effect.
+func (cfg *StateMachineConfig) SetOutSpecial(sticky, hasEnablePin bool, enablePinIndex uint8) {
+	cfg.ExecCtrl = (cfg.ExecCtrl &
+		^uint32(pio0_SM0_EXECCTRL_OUT_STICKY_Msk|
+			pio0_SM0_EXECCTRL_INLINE_OUT_EN_Msk|
+			pio0_SM0_EXECCTRL_OUT_EN_SEL_Msk)) |
+		(boolToBit(sticky) << pio0_SM0_EXECCTRL_OUT_STICKY_Pos) |
+		(boolToBit(hasEnablePin) << pio0_SM0_EXECCTRL_INLINE_OUT_EN_Pos) |
+		((uint32(enablePinIndex) << pio0_SM0_EXECCTRL_OUT_EN_SEL_Pos) & pio0_SM0_EXECCTRL_OUT_EN_SEL_Msk)
+}
+
+type MovStatus int
+
+const (
+	STATUS_TX_LESSTHAN MovStatus = iota
+	STATUS_RX_LESSTHAN
+)
+
+// SetMovStatus sets the source for 'mov status' instructions. The status is
+// all-ones if the selected FIFO level is less than statusN, otherwise all-zeroes.
+func (cfg *StateMachineConfig) SetMovStatus(status MovStatus, statusN uint8) {
+	cfg.ExecCtrl = (cfg.ExecCtrl & ^uint32(pio0_SM0_EXECCTRL_STATUS_SEL_Msk|pio0_SM0_EXECCTRL_STATUS_N_Msk)) |
+		(uint32(status) << pio0_SM0_EXECCTRL_STATUS_SEL_Pos) |
+		((uint32(statusN) << pio0_SM0_EXECCTRL_STATUS_N_Pos) & pio0_SM0_EXECCTRL_STATUS_N_Msk)
+}
+
+// SetClkDivRaw sets the raw CLKDIV register value.
+func (cfg *StateMachineConfig) SetClkDivRaw(clkdiv uint32) { cfg.ClkDiv = clkdiv }
+
+// SetExecCtrlRaw sets the raw EXECCTRL register value.
+func (cfg *StateMachineConfig) SetExecCtrlRaw(execctrl uint32) { cfg.ExecCtrl = execctrl }
+
+// SetShiftCtrlRaw sets the raw SHIFTCTRL register value.
+func (cfg *StateMachineConfig) SetShiftCtrlRaw(shiftctrl uint32) { cfg.ShiftCtrl = shiftctrl }
+
+// SetPinCtrlRaw sets the raw PINCTRL register value.
+func (cfg *StateMachineConfig) SetPinCtrlRaw(pinctrl uint32) { cfg.PinCtrl = pinctrl }
+
+// pinGroup is a range of consecutive pins used by a state machine.
+type pinGroup struct {
+	base, count uint8
+}
+
+// overlaps reports whether both groups are non-empty and share a pin.
+// Pin numbers wrap around modulo 32 as in the hardware.
+func (g pinGroup) overlaps(other pinGroup) bool {
+	for i := uint8(0); i < g.count; i++ {
+		if (g.base+i-other.base)&0x1f < other.count {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns ErrPinOutOfRange if the group runs past GPIO 29.
+// An empty group is always valid.
+func (g pinGroup) validate() error {
+	if g.count > 0 && int(g.base)+int(g.count) > 30 {
+		return ErrPinOutOfRange
+	}
+	return nil
+}
+
+// pinGroups decodes the 'out', 'set' and side-set pin groups of the
+// configuration. The side-set count excludes the enable bit of an optional side-set.
+func (cfg *StateMachineConfig) pinGroups() (out, set, sideSet pinGroup) {
+	field := func(msk, pos uint32) uint8 { return uint8((cfg.PinCtrl & msk) >> pos) }
+	out = pinGroup{
+		base:  field(pio0_SM0_PINCTRL_OUT_BASE_Msk, pio0_SM0_PINCTRL_OUT_BASE_Pos),
+		count: field(pio0_SM0_PINCTRL_OUT_COUNT_Msk, pio0_SM0_PINCTRL_OUT_COUNT_Pos),
+	}
+	set = pinGroup{
+		base:  field(pio0_SM0_PINCTRL_SET_BASE_Msk, pio0_SM0_PINCTRL_SET_BASE_Pos),
+		count: field(pio0_SM0_PINCTRL_SET_COUNT_Msk, pio0_SM0_PINCTRL_SET_COUNT_Pos),
+	}
+	sideSet = pinGroup{
+		base:  field(pio0_SM0_PINCTRL_SIDESET_BASE_Msk, pio0_SM0_PINCTRL_SIDESET_BASE_Pos),
+		count: field(pio0_SM0_PINCTRL_SIDESET_COUNT_Msk, pio0_SM0_PINCTRL_SIDESET_COUNT_Pos),
+	}
+	if cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_EN != 0 && sideSet.count > 0 {
+		sideSet.count-- // Enable bit is not a pin.
+	}
+	return out, set, sideSet
+}
+
+// ValidateConfigPins checks the pin mapping of the configuration before it is
+// applied to a state machine. It returns ErrPinOutOfRange if the 'out', 'set'
+// or side-set pin group runs past GPIO 29, and ErrPinConflict if side-set pins
+// overlap the 'out' or 'set' pins, where side-set silently takes priority.
+// The 'in' pin group has no count and is not checked.
+func ValidateConfigPins(cfg *StateMachineConfig) error {
+	out, set, sideSet := cfg.pinGroups()
+	for _, g := range [...]pinGroup{out, set, sideSet} {
+		if err := g.validate(); err != nil {
+			return err
+		}
+	}
+	if sideSet.overlaps(out) || sideSet.overlaps(set) {
+		return ErrPinConflict
+	}
+	return nil
+}
+
+func boolToBit(b bool) uint32 {
+	if b {
+		return 1
+	}
+	return 0
 }
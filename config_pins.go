@@ -0,0 +1,130 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import "machine"
+
+// SetSetPins sets the pins a PIO 'set' instruction modifies
+func (cfg *StateMachineConfig) SetSetPins(base machine.Pin, count uint8) {
+	cfg.PinCtrl = (cfg.PinCtrl & ^uint32(pio0_SM0_PINCTRL_SET_BASE_Msk|pio0_SM0_PINCTRL_SET_COUNT_Msk)) |
+		(uint32(base) << pio0_SM0_PINCTRL_SET_BASE_Pos) |
+		(uint32(count) << pio0_SM0_PINCTRL_SET_COUNT_Pos)
+}
+
+// SetOutPins sets the pins a PIO 'out' instruction modifies.
+func (cfg *StateMachineConfig) SetOutPins(base machine.Pin, count uint8) {
+	cfg.PinCtrl = (cfg.PinCtrl & ^uint32(pio0_SM0_PINCTRL_OUT_BASE_Msk|pio0_SM0_PINCTRL_OUT_COUNT_Msk)) |
+		(uint32(base) << pio0_SM0_PINCTRL_OUT_BASE_Pos) |
+		(uint32(count) << pio0_SM0_PINCTRL_OUT_COUNT_Pos)
+}
+
+// SetInPins sets the base pin for PIO 'in' instructions. Pins are
+// read relative to this base.
+func (cfg *StateMachineConfig) SetInPins(base machine.Pin) {
+	cfg.PinCtrl = (cfg.PinCtrl & ^uint32(pio0_SM0_PINCTRL_IN_BASE_Msk)) |
+		(uint32(base) << pio0_SM0_PINCTRL_IN_BASE_Pos)
+}
+
+// PinRange is a range of Count consecutive GPIOs starting at Base, as used by
+// the pin mapping of a state machine. The hardware wraps pin numbers modulo 32,
+// so a range running past GPIO 29 silently maps to missing pins or wraps around
+// to GPIO 0; the PinRange setters reject such ranges instead.
+type PinRange struct {
+	Base  machine.Pin
+	Count uint8
+}
+
+// Validate returns ErrPinOutOfRange if the range runs past GPIO 29.
+// An empty range is always valid.
+func (r PinRange) Validate() error {
+	return pinGroup{base: uint8(r.Base), count: r.Count}.validate()
+}
+
+// SetSetPinRange is like SetSetPins but validates r first. It returns
+// ErrPinOutOfRange if r is invalid or has more than the 5 pins 'set' can drive.
+func (cfg *StateMachineConfig) SetSetPinRange(r PinRange) error {
+	if r.Count > 5 {
+		return ErrPinOutOfRange
+	}
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	cfg.SetSetPins(r.Base, r.Count)
+	return nil
+}
+
+// SetOutPinRange is like SetOutPins but validates r first.
+func (cfg *StateMachineConfig) SetOutPinRange(r PinRange) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	cfg.SetOutPins(r.Base, r.Count)
+	return nil
+}
+
+// SetInPinRange is like SetInPins but validates r first. The hardware only
+// stores the base; Count is the number of pins the program reads with 'in pins'.
+func (cfg *StateMachineConfig) SetInPinRange(r PinRange) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	cfg.SetInPins(r.Base)
+	return nil
+}
+
+// SetJmpPin sets the GPIO used as the condition for 'jmp pin' instructions.
+func (cfg *StateMachineConfig) SetJmpPin(pin machine.Pin) {
+	cfg.ExecCtrl = (cfg.ExecCtrl & ^uint32(pio0_SM0_EXECCTRL_JMP_PIN_Msk)) |
+		(uint32(pin) << pio0_SM0_EXECCTRL_JMP_PIN_Pos)
+}
+
+// PinGroup describes the data pins driven by 'out' and the side-set pins of a
+// program that clocks data with side-set, like a parallel bus with a strobe.
+// Getting the side-set base wrong relative to the data pins makes the clock
+// appear on a data line, which Validate catches.
+type PinGroup struct {
+	OutBase      machine.Pin
+	OutCount     uint8
+	SideSetBase  machine.Pin
+	SideSetCount uint8 // Side-set pins, not including the enable bit of an optional side-set.
+}
+
+// AdjacentPinGroup returns a PinGroup with dataCount data pins starting at
+// dataBase immediately followed by sideSetCount side-set pins.
+func AdjacentPinGroup(dataBase machine.Pin, dataCount, sideSetCount uint8) PinGroup {
+	return PinGroup{
+		OutBase:      dataBase,
+		OutCount:     dataCount,
+		SideSetBase:  dataBase + machine.Pin(dataCount),
+		SideSetCount: sideSetCount,
+	}
+}
+
+// Validate returns ErrPinOutOfRange if either group runs past GPIO 29 and
+// ErrPinConflict if the side-set pins overlap the data pins.
+func (g PinGroup) Validate() error {
+	out := pinGroup{base: uint8(g.OutBase), count: g.OutCount}
+	sideSet := pinGroup{base: uint8(g.SideSetBase), count: g.SideSetCount}
+	for _, pg := range [...]pinGroup{out, sideSet} {
+		if err := pg.validate(); err != nil {
+			return err
+		}
+	}
+	if sideSet.overlaps(out) {
+		return ErrPinConflict
+	}
+	return nil
+}
+
+// Apply validates the group and sets the 'out' and side-set pin bases and the
+// 'out' pin count of cfg. The side-set bit count is set separately with SetSideSet
+// since it depends on whether the program's side-set is optional.
+func (g PinGroup) Apply(cfg *StateMachineConfig) error {
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	cfg.SetOutPins(g.OutBase, g.OutCount)
+	cfg.SetSidePins(g.SideSetBase)
+	return nil
+}
@@ -0,0 +1,261 @@
+package pio
+
+import "testing"
+
+func TestGetSideSet(t *testing.T) {
+	tests := []struct {
+		count         uint8
+		opt, pindirs  bool
+		wantSideSetEn uint32
+	}{
+		{0, false, false, 0},
+		{1, false, false, 0},
+		{2, true, false, pio0_SM0_EXECCTRL_SIDE_EN}, // 1 value bit plus the enable bit.
+		{3, false, true, 0},
+		{5, true, true, pio0_SM0_EXECCTRL_SIDE_EN},
+	}
+	for _, tt := range tests {
+		cfg := DefaultStateMachineConfig()
+		cfg.SetSideSet(tt.count, tt.opt, tt.pindirs)
+		count, opt, pindirs := cfg.GetSideSet()
+		if count != tt.count || opt != tt.opt || pindirs != tt.pindirs {
+			t.Errorf("SetSideSet(%d, %v, %v): GetSideSet = %d, %v, %v", tt.count, tt.opt, tt.pindirs, count, opt, pindirs)
+		}
+		if cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_EN != tt.wantSideSetEn {
+			t.Errorf("SetSideSet(%d, %v, %v): SIDE_EN not %#x", tt.count, tt.opt, tt.pindirs, tt.wantSideSetEn)
+		}
+		// The enable bit is counted in SIDESET_COUNT but is not a pin.
+		_, _, sideSet := cfg.pinGroups()
+		wantPins := tt.count
+		if tt.opt {
+			wantPins--
+		}
+		if sideSet.count != wantPins {
+			t.Errorf("SetSideSet(%d, %v, %v): %d side-set pins, want %d", tt.count, tt.opt, tt.pindirs, sideSet.count, wantPins)
+		}
+	}
+}
+
+func TestSetSideSetPindirs(t *testing.T) {
+	cfg := DefaultStateMachineConfig()
+	for _, opt := range []bool{false, true, false} {
+		for _, pindirs := range []bool{true, false, true} {
+			cfg.SetSideSet(2, opt, pindirs)
+			if got := cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_PINDIR != 0; got != pindirs {
+				t.Errorf("SetSideSet(2, %v, %v): SIDE_PINDIR = %v", opt, pindirs, got)
+			}
+			if got := cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_EN != 0; got != opt {
+				t.Errorf("SetSideSet(2, %v, %v): SIDE_EN = %v", opt, pindirs, got)
+			}
+			if other := cfg.ExecCtrl &^ (pio0_SM0_EXECCTRL_SIDE_PINDIR | pio0_SM0_EXECCTRL_SIDE_EN); other != DefaultStateMachineConfig().ExecCtrl {
+				t.Errorf("SetSideSet(2, %v, %v) changed other EXECCTRL bits: %#x", opt, pindirs, other)
+			}
+		}
+	}
+}
+
+func TestSetEndian(t *testing.T) {
+	tests := []struct {
+		set func(cfg *StateMachineConfig, msbFirst bool)
+		bit uint32
+	}{
+		{(*StateMachineConfig).SetInEndian, pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk},
+		{(*StateMachineConfig).SetOutEndian, pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk},
+	}
+	for _, tt := range tests {
+		cfg := DefaultStateMachineConfig()
+		cfg.SetInShift(true, true, 8)
+		cfg.SetOutShift(true, true, 16)
+		before := cfg.ShiftCtrl
+		for _, msbFirst := range []bool{true, false, true} {
+			tt.set(&cfg, msbFirst)
+			// Shift right, the SHIFTDIR bit set, is LSB first.
+			if got := cfg.ShiftCtrl&tt.bit == 0; got != msbFirst {
+				t.Errorf("msbFirst=%v: SHIFTDIR bit %#x = %v", msbFirst, tt.bit, !got)
+			}
+			if cfg.ShiftCtrl&^tt.bit != before&^tt.bit {
+				t.Errorf("msbFirst=%v: other SHIFTCTRL bits changed from %#x to %#x", msbFirst, before, cfg.ShiftCtrl)
+			}
+		}
+	}
+}
+
+func TestDelaySideSetBudget(t *testing.T) {
+	tests := []struct {
+		count                  uint8
+		opt                    bool
+		wantDelay, wantSideSet uint8
+	}{
+		{0, false, 5, 0},
+		{1, false, 4, 1},
+		{2, true, 3, 2}, // Enable bit takes a delay bit.
+		{3, false, 2, 3},
+		{5, false, 0, 5},
+		{5, true, 0, 5},
+	}
+	for _, tt := range tests {
+		cfg := DefaultStateMachineConfig()
+		cfg.SetSideSet(tt.count, tt.opt, false)
+		delay, sideSet := cfg.DelaySideSetBudget()
+		if delay != tt.wantDelay || sideSet != tt.wantSideSet {
+			t.Errorf("SetSideSet(%d, %v): budget = %d delay, %d side-set bits; want %d, %d", tt.count, tt.opt, delay, sideSet, tt.wantDelay, tt.wantSideSet)
+		}
+	}
+	// Out of range counts are clamped to the 5-bit field.
+	cfg := DefaultStateMachineConfig()
+	cfg.SetSideSet(7, false, false)
+	if delay, sideSet := cfg.DelaySideSetBudget(); delay != 0 || sideSet != 5 {
+		t.Errorf("SetSideSet(7): budget = %d, %d; want 0, 5", delay, sideSet)
+	}
+}
+
+func TestValidateConfigPins(t *testing.T) {
+	// The pin setters take a machine.Pin, so build PINCTRL directly.
+	config := func(out, set, sideSet pinGroup, sideSetOpt bool) *StateMachineConfig {
+		cfg := DefaultStateMachineConfig()
+		cfg.SetSideSet(sideSet.count, sideSetOpt, false)
+		cfg.PinCtrl |= uint32(out.base)<<pio0_SM0_PINCTRL_OUT_BASE_Pos |
+			uint32(out.count)<<pio0_SM0_PINCTRL_OUT_COUNT_Pos |
+			uint32(set.base)<<pio0_SM0_PINCTRL_SET_BASE_Pos |
+			uint32(set.count)<<pio0_SM0_PINCTRL_SET_COUNT_Pos |
+			uint32(sideSet.base)<<pio0_SM0_PINCTRL_SIDESET_BASE_Pos
+		return &cfg
+	}
+	tests := []struct {
+		out, set, sideSet pinGroup
+		sideSetOpt        bool
+		want              error
+	}{
+		{pinGroup{0, 8}, pinGroup{8, 2}, pinGroup{10, 2}, false, nil},
+		{pinGroup{22, 8}, pinGroup{0, 0}, pinGroup{0, 0}, false, nil},
+		{pinGroup{23, 8}, pinGroup{0, 0}, pinGroup{0, 0}, false, ErrPinOutOfRange},
+		{pinGroup{0, 1}, pinGroup{26, 5}, pinGroup{0, 0}, false, ErrPinOutOfRange},
+		{pinGroup{0, 1}, pinGroup{0, 0}, pinGroup{28, 3}, false, ErrPinOutOfRange},
+		{pinGroup{0, 1}, pinGroup{0, 0}, pinGroup{28, 3}, true, nil}, // Enable bit is not a pin.
+		{pinGroup{0, 8}, pinGroup{0, 0}, pinGroup{7, 1}, false, ErrPinConflict},
+		{pinGroup{0, 1}, pinGroup{4, 2}, pinGroup{3, 2}, false, ErrPinConflict},
+		{pinGroup{0, 8}, pinGroup{0, 2}, pinGroup{8, 1}, false, nil}, // 'out' and 'set' may share pins.
+	}
+	for _, tt := range tests {
+		err := ValidateConfigPins(config(tt.out, tt.set, tt.sideSet, tt.sideSetOpt))
+		if err != tt.want {
+			t.Errorf("out %v, set %v, side-set %v opt=%v: got %v, want %v", tt.out, tt.set, tt.sideSet, tt.sideSetOpt, err, tt.want)
+		}
+	}
+}
+
+func TestClearSideSet(t *testing.T) {
+	cfg := DefaultStateMachineConfig()
+	cfg.SetSideSet(3, true, true)
+	cfg.ClearSideSet()
+	if count, opt, pindirs := cfg.GetSideSet(); count != 0 || opt || pindirs {
+		t.Errorf("after ClearSideSet GetSideSet = %d, %v, %v; want 0, false, false", count, opt, pindirs)
+	}
+	if delay, sideSet := cfg.DelaySideSetBudget(); delay != 5 || sideSet != 0 {
+		t.Errorf("after ClearSideSet budget = %d delay, %d side-set bits; want 5, 0", delay, sideSet)
+	}
+	def := DefaultStateMachineConfig()
+	if cfg.ExecCtrl != def.ExecCtrl || cfg.PinCtrl != def.PinCtrl {
+		t.Errorf("ClearSideSet left EXECCTRL %#x PINCTRL %#x, want the defaults", cfg.ExecCtrl, cfg.PinCtrl)
+	}
+}
+
+func TestSetOutSpecialSticky(t *testing.T) {
+	cfg := DefaultStateMachineConfig()
+	cfg.SetOutSpecial(true, true, 3)
+	want := uint32(pio0_SM0_EXECCTRL_OUT_STICKY_Msk | pio0_SM0_EXECCTRL_INLINE_OUT_EN_Msk | 3<<pio0_SM0_EXECCTRL_OUT_EN_SEL_Pos)
+	if got := cfg.ExecCtrl &^ DefaultStateMachineConfig().ExecCtrl; got != want {
+		t.Errorf("SetOutSpecial(true, true, 3) set EXECCTRL bits %#x, want %#x", got, want)
+	}
+	// A replacement configuration without sticky output must clear every bit.
+	cfg.SetOutSpecial(false, false, 0)
+	if cfg.ExecCtrl != DefaultStateMachineConfig().ExecCtrl {
+		t.Errorf("SetOutSpecial(false, false, 0) left EXECCTRL %#x", cfg.ExecCtrl)
+	}
+}
+
+func TestShiftPresets(t *testing.T) {
+	const (
+		inMask  = pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk | pio0_SM0_SHIFTCTRL_AUTOPUSH_Msk | pio0_SM0_SHIFTCTRL_PUSH_THRESH_Msk
+		outMask = pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk | pio0_SM0_SHIFTCTRL_AUTOPULL_Msk | pio0_SM0_SHIFTCTRL_PULL_THRESH_Msk
+	)
+	tests := []struct {
+		name      string
+		set       func(cfg *StateMachineConfig, msbFirst bool)
+		input     bool
+		threshold uint32 // As encoded in SHIFTCTRL, 0 for 32.
+	}{
+		{"SetByteInput", (*StateMachineConfig).SetByteInput, true, 8},
+		{"SetHalfwordInput", (*StateMachineConfig).SetHalfwordInput, true, 16},
+		{"SetWordInput", (*StateMachineConfig).SetWordInput, true, 0},
+		{"SetByteOutput", (*StateMachineConfig).SetByteOutput, false, 8},
+		{"SetHalfwordOutput", (*StateMachineConfig).SetHalfwordOutput, false, 16},
+		{"SetWordOutput", (*StateMachineConfig).SetWordOutput, false, 0},
+	}
+	for _, tt := range tests {
+		for _, msbFirst := range []bool{true, false} {
+			cfg := DefaultStateMachineConfig()
+			tt.set(&cfg, msbFirst)
+			var want, mask uint32
+			if tt.input {
+				want = boolToBit(!msbFirst)<<pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos | pio0_SM0_SHIFTCTRL_AUTOPUSH |
+					tt.threshold<<pio0_SM0_SHIFTCTRL_PUSH_THRESH_Pos
+				mask = inMask
+			} else {
+				want = boolToBit(!msbFirst)<<pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos | pio0_SM0_SHIFTCTRL_AUTOPULL |
+					tt.threshold<<pio0_SM0_SHIFTCTRL_PULL_THRESH_Pos
+				mask = outMask
+			}
+			if got := cfg.ShiftCtrl & mask; got != want {
+				t.Errorf("%s(%v): SHIFTCTRL fields %#x, want %#x", tt.name, msbFirst, got, want)
+			}
+			if cfg.ShiftCtrl&^mask != DefaultStateMachineConfig().ShiftCtrl&^mask {
+				t.Errorf("%s(%v) changed the other direction's fields", tt.name, msbFirst)
+			}
+		}
+	}
+}
+
+func TestGetWrap(t *testing.T) {
+	cfg := DefaultStateMachineConfig()
+	for _, w := range [][2]uint8{{0, 31}, {3, 7}, {31, 31}, {0, 0}} {
+		cfg.SetWrap(w[0], w[1])
+		if target, top := cfg.GetWrap(); target != w[0] || top != w[1] {
+			t.Errorf("SetWrap(%d, %d): GetWrap = %d, %d", w[0], w[1], target, top)
+		}
+	}
+	// Program.DefaultConfig relocates the program's wrap to its offset.
+	p := &Program{Instructions: make([]uint16, 4), Origin: -1, WrapTarget: 1, Wrap: 3, SideSetCount: 1, SideSetOpt: true}
+	cfg = p.DefaultConfig(20)
+	if target, top := cfg.GetWrap(); target != 21 || top != 23 {
+		t.Errorf("DefaultConfig(20): GetWrap = %d, %d; want 21, 23", target, top)
+	}
+	if count, opt, pindirs := cfg.GetSideSet(); count != 2 || !opt || pindirs {
+		t.Errorf("DefaultConfig(20): GetSideSet = %d, %v, %v; want 2, true, false", count, opt, pindirs)
+	}
+}
+
+func TestShiftThreshold(t *testing.T) {
+	tests := []struct {
+		threshold uint16
+		want      uint32 // Encoded field, 0 means 32.
+	}{
+		{0, 0},
+		{1, 1},
+		{8, 8},
+		{31, 31},
+		{ShiftThresholdFull, 0},
+		{33, 0}, // Clamped to 32, not wrapped to 1.
+		{0xffff, 0},
+	}
+	for _, tt := range tests {
+		cfg := DefaultStateMachineConfig()
+		cfg.SetInShift(false, true, tt.threshold)
+		cfg.SetOutShift(false, true, tt.threshold)
+		push := (cfg.ShiftCtrl & pio0_SM0_SHIFTCTRL_PUSH_THRESH_Msk) >> pio0_SM0_SHIFTCTRL_PUSH_THRESH_Pos
+		pull := (cfg.ShiftCtrl & pio0_SM0_SHIFTCTRL_PULL_THRESH_Msk) >> pio0_SM0_SHIFTCTRL_PULL_THRESH_Pos
+		if push != tt.want || pull != tt.want {
+			t.Errorf("threshold %d: PUSH_THRESH %d, PULL_THRESH %d; want %d", tt.threshold, push, pull, tt.want)
+		}
+	}
+}
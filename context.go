@@ -0,0 +1,65 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"context"
+	"time"
+)
+
+// TxPutBlockingCtx is like TxPutBlocking but returns ctx.Err() if the context
+// is done before there is room in the TX FIFO.
+func (sm StateMachine) TxPutBlockingCtx(ctx context.Context, data uint32) error {
+	if err := waitCtx(ctx, sm.IsTxFIFOFull); err != nil {
+		return err
+	}
+	sm.TxPut(data)
+	return nil
+}
+
+// RxGetBlockingCtx is like RxGetBlocking but returns ctx.Err() if the context
+// is done before data is available in the RX FIFO.
+func (sm StateMachine) RxGetBlockingCtx(ctx context.Context) (uint32, error) {
+	if err := waitCtx(ctx, sm.IsRxFIFOEmpty); err != nil {
+		return 0, err
+	}
+	return sm.RxGet(), nil
+}
+
+// ExecBlockingCtx is like ExecBlocking but returns ctx.Err() if the context is
+// done before the instruction completes. The instruction is still stalled in
+// that case, which can be cleared by restarting the state machine.
+func (sm StateMachine) ExecBlockingCtx(ctx context.Context, instr uint16) error {
+	sm.Exec(instr)
+	return waitCtx(ctx, sm.IsStalled)
+}
+
+// WaitIRQCtx is like WaitIRQ but returns ctx.Err() if the context is done
+// before the IRQ flag is set. The flag is only cleared if it was set.
+func (pio *PIO) WaitIRQCtx(ctx context.Context, index uint8) error {
+	if index > 7 {
+		panic("invalid IRQ index")
+	}
+	mask := uint32(1) << index
+	err := waitCtx(ctx, func() bool { return pio.HW.IRQ.Get()&mask == 0 })
+	if err != nil {
+		return err
+	}
+	pio.HW.IRQ.Set(mask)
+	return nil
+}
+
+// WaitIdleTimeout is like WaitIdle but gives up after timeout, returning
+// context.DeadlineExceeded.
+func (sm StateMachine) WaitIdleTimeout(timeout time.Duration) error {
+	bit := sm.txStallBit()
+	sm.ClearTxStall()
+	start := time.Now()
+	for sm.PIO.HW.FDEBUG.Get()&bit == 0 {
+		if time.Since(start) >= timeout {
+			return context.DeadlineExceeded
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package pio
+
+import "strconv"
+
+// Instruction is a decoded PIO instruction. See DecodeInstruction.
+type Instruction struct {
+	// Raw is the encoded instruction.
+	Raw uint16
+	// Major is the opcode, one of the INSTR_BITS_* constants. PUSH and PULL
+	// are told apart as INSTR_BITS_PUSH and INSTR_BITS_PULL.
+	Major uint16
+	// HasSideSet is true if the instruction drives side-set pins.
+	HasSideSet bool
+	// SideSet is the side-set value, valid if HasSideSet is true.
+	SideSet uint8
+	// Delay is the number of delay cycles after the instruction.
+	Delay uint8
+}
+
+// DecodeInstruction splits an instruction into its opcode and its shared
+// delay/side-set field. sideSetCount is the number of side-set bits declared
+// with .side_set, not including the enable bit, and sideSetOpt is true for an
+// optional side-set, matching Program.SideSetCount and Program.SideSetOpt.
+//
+// The 5-bit delay/side-set field holds, from most to least significant bit,
+// the enable bit if side-set is optional, the side-set value and the delay. With
+// an optional side-set the enable bit takes one delay bit even on instructions
+// that do not side-set, and the value is only meaningful when the enable bit is set.
+func DecodeInstruction(instr uint16, sideSetCount uint8, sideSetOpt bool) Instruction {
+	totalBits := sideSetCount
+	if sideSetOpt {
+		totalBits++
+	}
+	if totalBits > 5 {
+		totalBits = 5
+	}
+	delayBits := 5 - totalBits
+	field := uint8(instr>>8) & 0x1f
+	d := Instruction{
+		Raw:   instr,
+		Major: instr & INSTR_BITS_Msk,
+		Delay: field & (1<<delayBits - 1),
+	}
+	if d.Major == INSTR_BITS_PUSH {
+		d.Major = instr & (INSTR_BITS_Msk | 0x80)
+	}
+	if sideSetOpt {
+		d.HasSideSet = field&0x10 != 0
+	} else {
+		d.HasSideSet = sideSetCount > 0
+	}
+	if d.HasSideSet {
+		d.SideSet = (field >> delayBits) & (1<<sideSetCount - 1)
+	}
+	return d
+}
+
+// DisassembleInstruction returns the pioasm source of an instruction, e.g.
+// "out pins, 8 side 0 [1]". See DecodeInstruction for the side-set arguments.
+func DisassembleInstruction(instr uint16, sideSetCount uint8, sideSetOpt bool) string {
+	d := DecodeInstruction(instr, sideSetCount, sideSetOpt)
+	s := disassembleOp(instr)
+	if d.HasSideSet {
+		s += " side " + strconv.Itoa(int(d.SideSet))
+	}
+	if d.Delay > 0 {
+		s += " [" + strconv.Itoa(int(d.Delay)) + "]"
+	}
+	return s
+}
+
+// Operand names indexed by their 3-bit encoding. Reserved encodings are empty.
+var (
+	jmpConds  = [8]string{"", "!x ", "x-- ", "!y ", "y-- ", "x!=y ", "pin ", "!osre "}
+	waitSrcs  = [4]string{"gpio", "pin", "irq", ""}
+	inSrcs    = [8]string{"pins", "x", "y", "null", "", "", "isr", "osr"}
+	outDests  = [8]string{"pins", "x", "y", "null", "pindirs", "pc", "isr", "exec"}
+	movDests  = [8]string{"pins", "x", "y", "", "exec", "pc", "isr", "osr"}
+	movOps    = [4]string{"", "!", "::", ""}
+	movSrcs   = [8]string{"pins", "x", "y", "null", "", "status", "isr", "osr"}
+	setDests  = [8]string{"pins", "x", "y", "", "pindirs", "", "", ""}
+	blockName = [2]string{"noblock", "block"}
+)
+
+// disassembleOp returns the pioasm source of the opcode and operands of an
+// instruction, without side-set and delay.
+func disassembleOp(instr uint16) string {
+	arg1 := (instr >> 5) & 7
+	arg2 := instr & 0x1f
+	itoa := func(v uint16) string { return strconv.Itoa(int(v)) }
+	bitCount := func(v uint16) string {
+		if v == 0 {
+			return "32"
+		}
+		return itoa(v)
+	}
+	irqIndex := func(v uint16) string {
+		if v&0x10 != 0 {
+			return itoa(v&7) + " rel"
+		}
+		return itoa(v & 7)
+	}
+	switch instr & INSTR_BITS_Msk {
+	case INSTR_BITS_JMP:
+		return "jmp " + jmpConds[arg1] + itoa(arg2)
+	case INSTR_BITS_WAIT:
+		src := waitSrcs[arg1&3]
+		if src == "" {
+			break
+		}
+		index := itoa(arg2)
+		if src == "irq" {
+			index = irqIndex(arg2)
+		}
+		return "wait " + itoa(arg1>>2) + " " + src + " " + index
+	case INSTR_BITS_IN:
+		if inSrcs[arg1] == "" {
+			break
+		}
+		return "in " + inSrcs[arg1] + ", " + bitCount(arg2)
+	case INSTR_BITS_OUT:
+		return "out " + outDests[arg1] + ", " + bitCount(arg2)
+	case INSTR_BITS_PUSH:
+		if arg2 != 0 {
+			break
+		}
+		block := blockName[arg1&1]
+		if arg1&4 == 0 {
+			if arg1&2 != 0 {
+				return "push iffull " + block
+			}
+			return "push " + block
+		}
+		if arg1&2 != 0 {
+			return "pull ifempty " + block
+		}
+		return "pull " + block
+	case INSTR_BITS_MOV:
+		op := movOps[arg2>>3&3]
+		dest, src := movDests[arg1], movSrcs[arg2&7]
+		if dest == "" || src == "" || (op == "" && arg2&0x18 != 0) {
+			break
+		}
+		if instr&0xff == 0x42 { // mov y, y
+			return "nop"
+		}
+		return "mov " + dest + ", " + op + src
+	case INSTR_BITS_IRQ:
+		if arg1&4 != 0 {
+			break
+		}
+		switch {
+		case arg1&2 != 0:
+			return "irq clear " + irqIndex(arg2)
+		case arg1&1 != 0:
+			return "irq wait " + irqIndex(arg2)
+		}
+		return "irq set " + irqIndex(arg2)
+	case INSTR_BITS_SET:
+		if setDests[arg1] == "" {
+			break
+		}
+		return "set " + setDests[arg1] + ", " + itoa(arg2)
+	}
+	return ".word 0x" + strconv.FormatUint(uint64(instr), 16)
+}
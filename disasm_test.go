@@ -0,0 +1,59 @@
+package pio
+
+import "testing"
+
+func TestDecodeInstructionSideSet(t *testing.T) {
+	const nop = 0xa042 // mov y, y
+	tests := []struct {
+		name       string
+		instr      uint16
+		count      uint8
+		opt        bool
+		hasSideSet bool
+		sideSet    uint8
+		delay      uint8
+	}{
+		{"no side-set", nop | 0x1f<<8, 0, false, false, 0, 31},
+		{"mandatory 1 bit", nop | 0x13<<8, 1, false, true, 1, 3},
+		{"mandatory 5 bits", nop | 0x15<<8, 5, false, true, 21, 0},
+		// The same field read with an optional side-set: the MSB is the enable bit.
+		{"optional 1 bit enabled", nop | 0x13<<8, 1, true, true, 0, 3},
+		{"optional 1 bit disabled", nop | 0x07<<8, 1, true, false, 0, 7},
+		{"optional 2 bits", nop | 0x19<<8, 2, true, true, 2, 1},
+		{"optional 4 bits", nop | 0x1f<<8, 4, true, true, 15, 0},
+		// Without the enable bit the value bits are not a side-set.
+		{"optional 2 bits disabled", nop | 0x0d<<8, 2, true, false, 0, 1},
+	}
+	for _, tt := range tests {
+		d := DecodeInstruction(tt.instr, tt.count, tt.opt)
+		if d.HasSideSet != tt.hasSideSet || d.SideSet != tt.sideSet || d.Delay != tt.delay {
+			t.Errorf("%s: got side-set %v %d delay %d, want %v %d delay %d", tt.name,
+				d.HasSideSet, d.SideSet, d.Delay, tt.hasSideSet, tt.sideSet, tt.delay)
+		}
+		if d.Major != INSTR_BITS_MOV {
+			t.Errorf("%s: got major %#x, want mov", tt.name, d.Major)
+		}
+	}
+}
+
+func TestDisassembleInstruction(t *testing.T) {
+	tests := []struct {
+		instr uint16
+		count uint8
+		opt   bool
+		want  string
+	}{
+		{EncodeOut(SrcDestPins, 8) | EncodeSideSet(1, 0) | EncodeDelay(1), 1, false, "out pins, 8 side 0 [1]"},
+		{EncodeOut(SrcDestPins, 8) | EncodeSideSet(1, 1), 1, false, "out pins, 8 side 1"},
+		{EncodePull(false, true) | EncodeSetSetOpt(1, 1), 1, true, "pull block side 1"},
+		{EncodePull(false, true) | EncodeDelay(3), 1, true, "pull block [3]"},
+		{EncodeJmp(5), 0, false, "jmp 5"},
+		{EncodeSet(SrcDestPinDirs, 3), 0, false, "set pindirs, 3"},
+	}
+	for _, tt := range tests {
+		got := DisassembleInstruction(tt.instr, tt.count, tt.opt)
+		if got != tt.want {
+			t.Errorf("DisassembleInstruction(%#04x, %d, %v) = %q, want %q", tt.instr, tt.count, tt.opt, got, tt.want)
+		}
+	}
+}
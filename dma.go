@@ -0,0 +1,335 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"device/rp"
+	"math/bits"
+	"runtime/volatile"
+	"unsafe"
+)
+
+// DMA data request (DREQ) numbers of the PIO FIFOs. PIO0 occupies DREQs 0..7
+// and PIO1 8..15; within a block the four TX FIFOs come before the four RX FIFOs.
+// See StateMachine.TxDREQ and StateMachine.RxDREQ.
+const (
+	DREQ_PIO0_TX0 = 0x0
+	DREQ_PIO0_TX1 = 0x1
+	DREQ_PIO0_TX2 = 0x2
+	DREQ_PIO0_TX3 = 0x3
+	DREQ_PIO0_RX0 = 0x4
+	DREQ_PIO0_RX1 = 0x5
+	DREQ_PIO0_RX2 = 0x6
+	DREQ_PIO0_RX3 = 0x7
+	DREQ_PIO1_TX0 = 0x8
+	DREQ_PIO1_TX1 = 0x9
+	DREQ_PIO1_TX2 = 0xa
+	DREQ_PIO1_TX3 = 0xb
+	DREQ_PIO1_RX0 = 0xc
+	DREQ_PIO1_RX1 = 0xd
+	DREQ_PIO1_RX2 = 0xe
+	DREQ_PIO1_RX3 = 0xf
+)
+
+// dmaChannel is a single DMA channel. See rp.DMA_Type.
+type dmaChannel struct {
+	READ_ADDR   volatile.Register32
+	WRITE_ADDR  volatile.Register32
+	TRANS_COUNT volatile.Register32
+	CTRL_TRIG   volatile.Register32
+
+	AL1_CTRL             volatile.Register32
+	AL1_READ_ADDR        volatile.Register32
+	AL1_WRITE_ADDR       volatile.Register32
+	AL1_TRANS_COUNT_TRIG volatile.Register32
+
+	AL2_CTRL            volatile.Register32
+	AL2_TRANS_COUNT     volatile.Register32
+	AL2_READ_ADDR       volatile.Register32
+	AL2_WRITE_ADDR_TRIG volatile.Register32
+
+	AL3_CTRL           volatile.Register32
+	AL3_WRITE_ADDR     volatile.Register32
+	AL3_TRANS_COUNT    volatile.Register32
+	AL3_READ_ADDR_TRIG volatile.Register32
+}
+
+// DMA channels usable on the RP2040.
+var dmaChannels = (*[12]dmaChannel)(unsafe.Pointer(rp.DMA))
+
+// getDMAChannel returns the DMA channel at index.
+func getDMAChannel(index uint8) *dmaChannel {
+	if index > 11 {
+		panic("invalid DMA channel index")
+	}
+	return &dmaChannels[index]
+}
+
+// dmaCtrl returns a CTRL_TRIG value for an enabled 32-bit transfer paced by dreq
+// that does not chain to other channels.
+func dmaCtrl(channel uint8, dreq uint32, incrRead, incrWrite bool) uint32 {
+	const dataSize32 = 2
+	return rp.DMA_CH0_CTRL_TRIG_EN |
+		(boolToBit(incrRead) << rp.DMA_CH0_CTRL_TRIG_INCR_READ_Pos) |
+		(boolToBit(incrWrite) << rp.DMA_CH0_CTRL_TRIG_INCR_WRITE_Pos) |
+		(dataSize32 << rp.DMA_CH0_CTRL_TRIG_DATA_SIZE_Pos) |
+		(dreq << rp.DMA_CH0_CTRL_TRIG_TREQ_SEL_Pos) |
+		// Chaining a channel to itself disables chaining.
+		(uint32(channel) << rp.DMA_CH0_CTRL_TRIG_CHAIN_TO_Pos)
+}
+
+// ChainSMs configures and starts DMA channel dmaCh so that every word pushed
+// to the producer's RX FIFO is moved to the consumer's TX FIFO, with no CPU
+// intervention.
+//
+// The transfer is paced by the producer's RX DREQ, so words are only read when
+// available. The consumer must drain its TX FIFO at least as fast as the producer
+// fills its RX FIFO, otherwise words are dropped and the consumer's TXOVER flag is set.
+// Both state machines may be on different PIO blocks.
+//
+// The consumer should be initialized and enabled before the producer so no
+// data is in flight when the channel starts. The channel runs for 2^32-1 transfers;
+// abort it through the DMA CHAN_ABORT register to stop it earlier.
+func ChainSMs(producer, consumer StateMachine, dmaCh uint8) {
+	ch := getDMAChannel(dmaCh)
+	ch.READ_ADDR.Set(uint32(uintptr(unsafe.Pointer(producer.rx()))))
+	ch.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(consumer.tx()))))
+	ch.TRANS_COUNT.Set(0xffff_ffff)
+	ch.CTRL_TRIG.Set(dmaCtrl(dmaCh, producer.RxDREQ(), false, false))
+}
+
+// BlitWords moves words into the state machine's TX FIFO with DMA channel
+// channel, paced by the TX DREQ, and blocks until the transfer completes.
+// If the channel is still busy with a previous transfer it waits for it first,
+// so consecutive blits never overlap.
+//
+// When BlitWords returns the words have left memory but the last few may still
+// be in the TX FIFO; call WaitIdle to wait until they have been shifted out.
+// words must not be modified while the transfer runs.
+func BlitWords(sm StateMachine, channel uint8, words []uint32) {
+	StartBlitWords(sm, channel, words)
+	for DMABusy(channel) {
+	}
+}
+
+// StartBlitWords is like BlitWords but returns as soon as the transfer has
+// started. Poll DMABusy to know when it completes. The caller must keep
+// words referenced and unmodified until then.
+func StartBlitWords(sm StateMachine, channel uint8, words []uint32) {
+	if len(words) == 0 {
+		return
+	}
+	ch := getDMAChannel(channel)
+	for DMABusy(channel) {
+	}
+	ch.READ_ADDR.Set(uint32(uintptr(unsafe.Pointer(&words[0]))))
+	ch.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(sm.tx()))))
+	ch.TRANS_COUNT.Set(uint32(len(words)))
+	ch.CTRL_TRIG.Set(dmaCtrl(channel, sm.TxDREQ(), true, false))
+}
+
+// CaptureWords fills dst with words from the state machine's RX FIFO using DMA
+// channel channel, paced by the RX DREQ, and blocks until dst is full. It is the
+// receive counterpart of BlitWords.
+func CaptureWords(sm StateMachine, channel uint8, dst []uint32) {
+	StartCaptureWords(sm, channel, dst)
+	for DMABusy(channel) {
+	}
+}
+
+// StartCaptureWords is like CaptureWords but returns as soon as the transfer
+// has started. Poll DMABusy to know when dst is full. The caller must keep dst
+// referenced and not read it until then.
+func StartCaptureWords(sm StateMachine, channel uint8, dst []uint32) {
+	if len(dst) == 0 {
+		return
+	}
+	ch := getDMAChannel(channel)
+	for DMABusy(channel) {
+	}
+	ch.READ_ADDR.Set(uint32(uintptr(unsafe.Pointer(sm.rx()))))
+	ch.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(&dst[0]))))
+	ch.TRANS_COUNT.Set(uint32(len(dst)))
+	ch.CTRL_TRIG.Set(dmaCtrl(channel, sm.RxDREQ(), false, true))
+}
+
+// DMABusy reports whether DMA channel channel is still transferring.
+func DMABusy(channel uint8) bool {
+	return getDMAChannel(channel).CTRL_TRIG.Get()&rp.DMA_CH0_CTRL_TRIG_BUSY != 0
+}
+
+// abortDMAChannels disables and aborts the channels in mask, waiting for
+// in-flight transfers to finish.
+func abortDMAChannels(mask uint32) {
+	for i := uint8(0); i < 12; i++ {
+		if mask&(1<<i) != 0 {
+			// Disable first so an abort does not fire a chain trigger.
+			getDMAChannel(i).AL1_CTRL.ClearBits(rp.DMA_CH0_CTRL_TRIG_EN)
+		}
+	}
+	rp.DMA.CHAN_ABORT.Set(mask)
+	for rp.DMA.CHAN_ABORT.Get()&mask != 0 {
+	}
+}
+
+// NewRingBuffer allocates a buffer of words 32-bit words (a power of two)
+// aligned to its size in bytes, as required by RxRing.
+func NewRingBuffer(words int) []uint32 {
+	buf := make([]uint32, 2*words)
+	addr := uintptr(unsafe.Pointer(&buf[0]))
+	size := uintptr(words) * 4
+	start := (size - addr%size) % size / 4
+	return buf[start : start+uintptr(words) : start+uintptr(words)]
+}
+
+// dmaControlWords holds, per control DMA channel, the word a control channel
+// writes into the data channel it re-arms, as used by RxRing and StreamRing. It lives in a package variable so
+// its address is stable and the garbage collector keeps it.
+var dmaControlWords [12]uint32
+
+// dmaStreamBuffers keeps buffers read by running DMA streams reachable for the
+// garbage collector, which cannot see DMA references.
+var dmaStreamBuffers [12][]uint32
+
+// RxRing is a continuous capture of a state machine's RX FIFO into a ring
+// buffer using DMA address wrapping. Once started the capture runs
+// forever without CPU intervention; the CPU reads the samples behind the DMA
+// write position with Read.
+//
+// Two DMA channels are used: the data channel moves words from the RX FIFO
+// into the ring and, each time its transfer count runs out, chains to the
+// control channel which re-arms the data channel's transfer count.
+//
+// The control word and buf are kept in package storage indexed by channel, so
+// they stay valid for the DMA even if the RxRing is dropped without Stop.
+type RxRing struct {
+	buf    []uint32
+	dataCh uint8
+	ctrlCh uint8
+	// read is the index of the next word to be returned by Read.
+	read int
+}
+
+// StartRxRing starts a continuous DMA capture of the state machine's RX FIFO
+// into buf using DMA channels dataCh and ctrlCh. buf must be a power of two
+// between 2 and 8192 words long and aligned to its size in bytes; see NewRingBuffer.
+func (sm StateMachine) StartRxRing(buf []uint32, dataCh, ctrlCh uint8) (*RxRing, error) {
+	if dataCh == ctrlCh {
+		return nil, ErrSameDMAChannel
+	}
+	n := len(buf)
+	if n < 2 || n > 8192 || n&(n-1) != 0 {
+		return nil, ErrRingBufferSize
+	}
+	if uintptr(unsafe.Pointer(&buf[0]))%uintptr(4*n) != 0 {
+		return nil, ErrRingBufferAlign
+	}
+	r := &RxRing{
+		buf:    buf,
+		dataCh: dataCh,
+		ctrlCh: ctrlCh,
+	}
+	data := getDMAChannel(dataCh)
+	ctrl := getDMAChannel(ctrlCh)
+	dmaStreamBuffers[dataCh] = buf
+	// Largest multiple of the ring length that fits in TRANS_COUNT so the
+	// write position is continuous across reloads.
+	dmaControlWords[ctrlCh] = uint32(0x1_0000_0000 - uint64(n))
+
+	ctrl.READ_ADDR.Set(uint32(uintptr(unsafe.Pointer(&dmaControlWords[ctrlCh]))))
+	ctrl.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(&data.AL1_TRANS_COUNT_TRIG))))
+	ctrl.TRANS_COUNT.Set(1)
+	ctrl.AL1_CTRL.Set(dmaCtrl(ctrlCh, rp.DMA_CH0_CTRL_TRIG_TREQ_SEL_PERMANENT, false, false))
+
+	ringBits := uint32(bits.TrailingZeros32(uint32(4 * n)))
+	dataCtrl := dmaCtrl(dataCh, sm.RxDREQ(), false, true)
+	dataCtrl = (dataCtrl &^ rp.DMA_CH0_CTRL_TRIG_CHAIN_TO_Msk) |
+		(uint32(ctrlCh) << rp.DMA_CH0_CTRL_TRIG_CHAIN_TO_Pos) |
+		(ringBits << rp.DMA_CH0_CTRL_TRIG_RING_SIZE_Pos) |
+		rp.DMA_CH0_CTRL_TRIG_RING_SEL // Wrap the write address.
+	data.READ_ADDR.Set(uint32(uintptr(unsafe.Pointer(sm.rx()))))
+	data.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(&buf[0]))))
+	data.TRANS_COUNT.Set(dmaControlWords[ctrlCh])
+	data.CTRL_TRIG.Set(dataCtrl)
+	return r, nil
+}
+
+// WriteIndex returns the index in the ring buffer the DMA will write next.
+func (r *RxRing) WriteIndex() int {
+	written := dmaControlWords[r.ctrlCh] - getDMAChannel(r.dataCh).TRANS_COUNT.Get()
+	return int(written % uint32(len(r.buf)))
+}
+
+// Read copies words captured since the last call to Read into dst and
+// returns the number of words copied. If the DMA has lapped the reader by
+// more than the ring length the oldest data is silently overwritten;
+// Read must be called often enough to keep up with the capture rate.
+func (r *RxRing) Read(dst []uint32) int {
+	w := r.WriteIndex()
+	n := 0
+	for r.read != w && n < len(dst) {
+		dst[n] = r.buf[r.read]
+		r.read = (r.read + 1) & (len(r.buf) - 1)
+		n++
+	}
+	return n
+}
+
+// Stop aborts the capture and releases both DMA channels.
+func (r *RxRing) Stop() {
+	abortDMAChannels(1<<r.dataCh | 1<<r.ctrlCh)
+	dmaStreamBuffers[r.dataCh] = nil
+}
+
+// TxRingStream is a continuous DMA stream of a buffer into a state machine's
+// TX FIFO. See StateMachine.StreamRing.
+type TxRingStream struct {
+	dataCh uint8
+	ctrlCh uint8
+}
+
+// StreamRing starts streaming framebuffer into the state machine's TX FIFO
+// over and over, without CPU intervention, as needed for continuous display refresh.
+//
+// Two DMA channels are chained in a ring: ch0 moves the framebuffer into the TX
+// FIFO, paced by the state machine's TX DREQ, and when done chains to ch1. ch1
+// writes the framebuffer start address back to ch0's read address trigger
+// register, which restarts ch0 with its reloaded transfer count.
+//
+// The framebuffer may be modified while streaming, at the cost of tearing.
+// Call Stop on the returned stream to stop cleanly. ErrEmptyBuffer is returned
+// for an empty framebuffer and ErrSameDMAChannel if ch0 and ch1 are the same.
+func (sm StateMachine) StreamRing(framebuffer []uint32, ch0, ch1 uint8) (*TxRingStream, error) {
+	if len(framebuffer) == 0 {
+		return nil, ErrEmptyBuffer
+	}
+	if ch0 == ch1 {
+		return nil, ErrSameDMAChannel
+	}
+	data := getDMAChannel(ch0)
+	ctrl := getDMAChannel(ch1)
+	dmaStreamBuffers[ch0] = framebuffer
+	dmaControlWords[ch1] = uint32(uintptr(unsafe.Pointer(&framebuffer[0])))
+
+	ctrl.READ_ADDR.Set(uint32(uintptr(unsafe.Pointer(&dmaControlWords[ch1]))))
+	ctrl.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(&data.AL3_READ_ADDR_TRIG))))
+	ctrl.TRANS_COUNT.Set(1)
+	ctrl.AL1_CTRL.Set(dmaCtrl(ch1, rp.DMA_CH0_CTRL_TRIG_TREQ_SEL_PERMANENT, false, false))
+
+	dataCtrl := dmaCtrl(ch0, sm.TxDREQ(), true, false)
+	dataCtrl = (dataCtrl &^ rp.DMA_CH0_CTRL_TRIG_CHAIN_TO_Msk) |
+		(uint32(ch1) << rp.DMA_CH0_CTRL_TRIG_CHAIN_TO_Pos)
+	data.READ_ADDR.Set(dmaControlWords[ch1])
+	data.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(sm.tx()))))
+	data.TRANS_COUNT.Set(uint32(len(framebuffer)))
+	data.CTRL_TRIG.Set(dataCtrl)
+	return &TxRingStream{dataCh: ch0, ctrlCh: ch1}, nil
+}
+
+// Stop stops the stream and releases both DMA channels. Words already in the
+// TX FIFO are still shifted out by the state machine.
+func (s *TxRingStream) Stop() {
+	abortDMAChannels(1<<s.dataCh | 1<<s.ctrlCh)
+	dmaStreamBuffers[s.dataCh] = nil
+}
@@ -0,0 +1,142 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"device/rp"
+	"machine"
+)
+
+// SMDriver is a state machine bound to a loaded program and its pins.
+// It is created with PIO.NewDriver.
+type SMDriver struct {
+	// CheckTxOverflow makes Write report ErrTxOverflow when a word written to
+	// the TX FIFO was dropped during the batch, by this or any other writer
+	// such as DMA or TxWriter, turning silent data loss into an error.
+	CheckTxOverflow bool
+
+	sm      StateMachine
+	program *Program
+	offset  uint8
+}
+
+// NewDriver claims the state machine at smIndex, loads program, routes the
+// configured 'out', 'set' and side-set pins to the PIO block as outputs and
+// initializes the state machine with cfg, leaving it disabled. Call Start to run it.
+//
+// The wrap of cfg is overwritten with the program's wrap relocated to the
+// offset the program was loaded at, so cfg may be built from DefaultStateMachineConfig.
+// ErrPinOutOfRange is returned, with nothing claimed or loaded, if a pin group
+// runs past GPIO 29.
+func (pio *PIO) NewDriver(program *Program, cfg StateMachineConfig, smIndex uint8) (*SMDriver, error) {
+	if smIndex > 3 {
+		return nil, ErrInvalidSMIndex
+	}
+	out, set, sideSet := cfg.pinGroups()
+	for _, g := range [...]pinGroup{out, set, sideSet} {
+		if err := g.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if !pio.ClaimStateMachine(smIndex) {
+		return nil, ErrStateMachineClaimed
+	}
+	offset, err := pio.AddProgram(program.Instructions, program.Origin)
+	if err != nil {
+		pio.UnclaimStateMachine(smIndex)
+		return nil, err
+	}
+	cfg.SetWrap(offset+program.WrapTarget, offset+program.Wrap)
+	sm := pio.StateMachine(smIndex)
+	sm.Init(offset, cfg)
+	sm.initConfigPins(&cfg)
+	return &SMDriver{sm: sm, program: program, offset: offset}, nil
+}
+
+// SetupProgram claims an unused state machine, loads program wherever there is
+// space and initializes the state machine with cfg, leaving it disabled. It
+// returns the state machine and the offset the program was loaded at.
+//
+// As with NewDriver, the wrap of cfg is overwritten with the program's wrap
+// relocated to that offset. Pins are not routed to the PIO block. If no state
+// machine is free ErrNoFreeStateMachine is returned; if the program does not fit
+// the error wraps ErrOutOfProgramSpace. Nothing is claimed or loaded on error.
+func (pio *PIO) SetupProgram(program *Program, cfg StateMachineConfig) (StateMachine, uint8, error) {
+	sm, err := pio.ClaimUnusedStateMachine()
+	if err != nil {
+		return StateMachine{}, 0, err
+	}
+	offset, err := pio.AddProgram(program.Instructions, program.Origin)
+	if err != nil {
+		pio.UnclaimStateMachine(sm.index)
+		return StateMachine{}, 0, err
+	}
+	cfg.SetWrap(offset+program.WrapTarget, offset+program.Wrap)
+	sm.Init(offset, cfg)
+	return sm, offset, nil
+}
+
+// initConfigPins routes the 'out', 'set' and side-set pin groups of cfg to the
+// PIO block and sets them as outputs. Side-set pins driving pin directions are
+// routed but their direction is left to the program. The groups must have been
+// validated by the caller.
+func (sm StateMachine) initConfigPins(cfg *StateMachineConfig) {
+	out, set, sideSet := cfg.pinGroups()
+	sideDirs := cfg.ExecCtrl&rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR != 0
+
+	mode := sm.PIO.PinMode()
+	route := func(base, count uint8, output bool) {
+		for i := uint8(0); i < count; i++ {
+			machine.Pin(base + i).Configure(machine.PinConfig{Mode: mode})
+		}
+		if output && count > 0 {
+			sm.SetConsecutivePinDirs(machine.Pin(base), count, true)
+		}
+	}
+	route(out.base, out.count, true)
+	route(set.base, set.count, true)
+	route(sideSet.base, sideSet.count, !sideDirs)
+}
+
+// StateMachine returns the state machine driven by d.
+func (d *SMDriver) StateMachine() StateMachine { return d.sm }
+
+// Offset returns the offset the program was loaded at.
+func (d *SMDriver) Offset() uint8 { return d.offset }
+
+// Start enables the state machine.
+func (d *SMDriver) Start() { d.sm.SetEnabled(true) }
+
+// Stop disables the state machine. It can be resumed with Start.
+func (d *SMDriver) Stop() { d.sm.SetEnabled(false) }
+
+// Write writes data into the TX FIFO, blocking while it is full.
+// See CheckTxOverflow.
+func (d *SMDriver) Write(data []uint32) error {
+	if d.CheckTxOverflow {
+		d.sm.LastTxOverflowed() // Clear stale flag.
+	}
+	for _, v := range data {
+		d.sm.TxPutBlocking(v)
+	}
+	if d.CheckTxOverflow && d.sm.LastTxOverflowed() {
+		return ErrTxOverflow
+	}
+	return nil
+}
+
+// Read fills dst with words from the RX FIFO, blocking while it is empty.
+func (d *SMDriver) Read(dst []uint32) {
+	for i := range dst {
+		dst[i] = d.sm.RxGetBlocking()
+	}
+}
+
+// Close stops the state machine, removes the program from instruction memory
+// and releases the state machine claim. The driver must not be used afterwards.
+func (d *SMDriver) Close() error {
+	d.Stop()
+	d.sm.PIO.UnclaimStateMachine(d.sm.index)
+	return d.sm.PIO.RemoveProgram(d.offset)
+}
@@ -0,0 +1,123 @@
+package pio
+
+import (
+	"errors"
+	"strconv"
+)
+
+// Sentinel errors returned by the checked functions of this package.
+// Compare with errors.Is.
+var (
+	// ErrInvalidSMIndex is returned for a state machine index outside 0..3.
+	ErrInvalidSMIndex = errors.New("pio: invalid state machine index")
+	// ErrProgramTooLarge is returned when a program exceeds the 32 instruction memory slots.
+	ErrProgramTooLarge = errors.New("pio: program larger than 32 instructions")
+	// ErrProgramNotRelocatable is returned when a program with a fixed origin is relocated.
+	ErrProgramNotRelocatable = errors.New("pio: program has fixed origin")
+	// ErrJmpOutOfRange is returned when a JMP targets an address outside its program.
+	ErrJmpOutOfRange = errors.New("pio: jmp target outside program")
+	// ErrSideSetMismatch is returned when linking programs with different side-set settings.
+	ErrSideSetMismatch = errors.New("pio: programs have different side-set settings")
+	// ErrSideSetBudgetExceeded is returned when side-set bits or values do not
+	// fit in the 5-bit delay/side-set field.
+	ErrSideSetBudgetExceeded = errors.New("pio: side-set exceeds delay/side-set field")
+	// ErrDelayTooLarge is returned when a delay does not fit in the delay bits
+	// left over by side-set.
+	ErrDelayTooLarge = errors.New("pio: delay too large for side-set configuration")
+	// ErrSetValueOutOfRange is returned by EncodeSetChecked for values that do not fit SET's immediate.
+	ErrSetValueOutOfRange = errors.New("pio: set value out of range 0..31")
+	// ErrPinOutOfRange is returned when a pin number falls outside the RP2040 GPIOs 0..29.
+	ErrPinOutOfRange = errors.New("pio: pin out of range 0..29")
+	// ErrPinNotRouted is returned by ConfigurePinsForOutput for a pin whose GPIO
+	// function is not a PIO block.
+	ErrPinNotRouted = errors.New("pio: pin not routed to a PIO block")
+	// ErrPinConflict is returned when pin groups of a configuration overlap.
+	ErrPinConflict = errors.New("pio: overlapping pin groups")
+	// ErrInvalidBlob is returned by LoadProgramBlob for malformed program blobs
+	// and by MarshalBlob for programs that cannot be encoded as one.
+	ErrInvalidBlob = errors.New("pio: invalid program blob")
+	// ErrOutOfProgramSpace is returned when no free range of instruction memory fits a program.
+	ErrOutOfProgramSpace = errors.New("pio: out of program space")
+	// ErrNoSpaceAtOffset is returned when a program overlaps used instruction memory at the requested offset.
+	ErrNoSpaceAtOffset = errors.New("pio: program space unavailable at offset")
+	// ErrProgramSizeMismatch is returned when a replacement program differs in length from the loaded one.
+	ErrProgramSizeMismatch = errors.New("pio: program size mismatch")
+	// ErrProgramNotLoaded is returned when no program was loaded at the given offset.
+	ErrProgramNotLoaded = errors.New("pio: program not loaded at offset")
+	// ErrTxOverflow is returned when the TX FIFO overflowed and data was dropped.
+	ErrTxOverflow = errors.New("pio: TX FIFO overflow, data dropped")
+	// ErrNoFreeStateMachine is returned when all state machines of a PIO block are claimed.
+	ErrNoFreeStateMachine = errors.New("pio: no free state machine")
+	// ErrStateMachineClaimed is returned when a requested state machine is already claimed.
+	ErrStateMachineClaimed = errors.New("pio: state machine already claimed")
+	// ErrInputNotConsumed is returned by RunOnce when the program stopped taking
+	// words from the TX FIFO before all inputs were consumed.
+	ErrInputNotConsumed = errors.New("pio: program did not consume all input")
+	// ErrFrequencyOutOfRange is returned when a requested frequency cannot be
+	// reached with the state machine clock divider.
+	ErrFrequencyOutOfRange = errors.New("pio: frequency out of range")
+	// ErrRingBufferSize is returned for a DMA ring buffer whose length is not a
+	// power of two between 2 and 8192 words.
+	ErrRingBufferSize = errors.New("pio: ring buffer length must be a power of two between 2 and 8192 words")
+	// ErrRingBufferAlign is returned for a DMA ring buffer not aligned to its size in bytes.
+	ErrRingBufferAlign = errors.New("pio: ring buffer not aligned to its size")
+	// ErrEmptyBuffer is returned when a DMA transfer is given an empty buffer.
+	ErrEmptyBuffer = errors.New("pio: empty DMA buffer")
+	// ErrSameDMAChannel is returned when a DMA ring is given the same channel for data and control.
+	ErrSameDMAChannel = errors.New("pio: data and control DMA channels must differ")
+	// ErrInvalidIndex is returned for an instruction index outside the program.
+	ErrInvalidIndex = errors.New("pio: instruction index out of range")
+	// ErrInvalidLaneCount is returned by ConfigureWideOutput for a lane count other than 2 or 4.
+	ErrInvalidLaneCount = errors.New("pio: wide output lanes must be 2 or 4")
+	// ErrInvalidLane is returned by WideOutput.Lane for a lane index out of range.
+	ErrInvalidLane = errors.New("pio: wide output lane out of range")
+	// ErrUnbalancedWrite is returned by WideOutput.Write when the data length
+	// is not a multiple of the number of lanes.
+	ErrUnbalancedWrite = errors.New("pio: wide output data not a multiple of lanes")
+)
+
+// ProgramSpaceError is returned when a program cannot be loaded into
+// instruction memory. It wraps ErrOutOfProgramSpace or ErrNoSpaceAtOffset so
+// it can be checked with errors.Is, and describes the instruction memory
+// state at the time of the failure.
+type ProgramSpaceError struct {
+	Err error
+	// Length is the length of the program that did not fit.
+	Length int
+	// Offset is the requested offset, or -1 if any offset was acceptable.
+	Offset int
+	// UsedSpaceMask is the instruction memory usage when loading failed.
+	UsedSpaceMask uint32
+}
+
+func (e *ProgramSpaceError) Error() string {
+	free := 0
+	for i := 0; i < 32; i++ {
+		if e.UsedSpaceMask&(1<<i) == 0 {
+			free++
+		}
+	}
+	msg := e.Err.Error() + ": need " + strconv.Itoa(e.Length) + " slots"
+	if e.Offset >= 0 {
+		msg += " at offset " + strconv.Itoa(e.Offset)
+	}
+	return msg + ", " + strconv.Itoa(free) + " free, used " + usedSpaceString(e.UsedSpaceMask)
+}
+
+func (e *ProgramSpaceError) Unwrap() error { return e.Err }
+
+// usedSpaceString renders an instruction memory usage mask as a bitmap,
+// slot 0 first, with 'x' for used and '.' for free slots.
+func usedSpaceString(mask uint32) string {
+	var buf [34]byte
+	buf[0] = '['
+	for i := 0; i < 32; i++ {
+		if mask&(1<<i) != 0 {
+			buf[i+1] = 'x'
+		} else {
+			buf[i+1] = '.'
+		}
+	}
+	buf[33] = ']'
+	return string(buf[:])
+}
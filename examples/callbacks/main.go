@@ -0,0 +1,64 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+const dataPin = machine.GP15
+
+// Shift each word out on a single pin, LSB first:
+//
+//	.wrap_target
+//	    out pins, 1
+//	.wrap
+var serialInstructions = []uint16{
+	pio.EncodeOut(pio.SrcDestPins, 1),
+}
+
+const wordsPerBurst = 256
+
+var (
+	sm      = pio.PIO0.StateMachine(0)
+	counter uint32
+	sent    int
+)
+
+// refill is called from interrupt context while the TX FIFO has room.
+func refill() {
+	for !sm.IsTxFIFOFull() && sent < wordsPerBurst {
+		sm.TxPut(counter)
+		counter++
+		sent++
+	}
+	if sent == wordsPerBurst {
+		// Nothing left to send, stop the interrupt from firing.
+		sm.OnTxNotFull(nil)
+	}
+}
+
+func main() {
+	time.Sleep(2 * time.Second)
+	offset, err := sm.PIO.AddProgram(serialInstructions, -1)
+	if err != nil {
+		panic(err.Error())
+	}
+	cfg := pio.DefaultStateMachineConfig()
+	if err := sm.ConfigureOutPins(&cfg, dataPin, 1); err != nil {
+		panic(err.Error())
+	}
+	cfg.SetWrap(offset, offset)
+	cfg.SetOutShift(true, true, pio.ShiftThresholdFull)
+	cfg.SetClkDivIntFrac(1000, 0)
+	sm.Init(offset, cfg)
+	sm.SetEnabled(true)
+
+	for {
+		sent = 0
+		sm.OnTxNotFull(refill)
+		time.Sleep(time.Second)
+		println("words sent:", counter)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+const (
+	outPin     = machine.GP15
+	dmaChannel = 0
+)
+
+// The producer inverts each word written by the CPU:
+//
+//	.wrap_target
+//	    pull block
+//	    mov isr, ~osr
+//	    push block
+//	.wrap
+var producerInstructions = []uint16{
+	pio.EncodePull(false, true),
+	pio.EncodeMovNot(pio.SrcDestISR, pio.SrcDestOSR),
+	pio.EncodePush(false, true),
+}
+
+// The consumer serializes each word onto a single pin, LSB first:
+//
+//	.wrap_target
+//	    out pins, 1
+//	.wrap
+var consumerInstructions = []uint16{
+	pio.EncodeOut(pio.SrcDestPins, 1),
+}
+
+func main() {
+	time.Sleep(2 * time.Second)
+	block := pio.PIO0
+	producer := block.StateMachine(0)
+	consumer := block.StateMachine(1)
+
+	producerOffset, err := block.AddProgram(producerInstructions, -1)
+	if err != nil {
+		panic(err.Error())
+	}
+	consumerOffset, err := block.AddProgram(consumerInstructions, -1)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Consumer first so the chain is ready before data flows.
+	outPin.Configure(machine.PinConfig{Mode: machine.PinPIO0})
+	consumer.SetConsecutivePinDirs(outPin, 1, true)
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetWrap(consumerOffset, consumerOffset)
+	cfg.SetOutPins(outPin, 1)
+	cfg.SetOutShift(true, true, pio.ShiftThresholdFull)
+	cfg.SetClkDivIntFrac(1000, 0)
+	consumer.Init(consumerOffset, cfg)
+	consumer.SetEnabled(true)
+
+	cfg = pio.DefaultStateMachineConfig()
+	cfg.SetWrap(producerOffset, producerOffset+uint8(len(producerInstructions))-1)
+	producer.Init(producerOffset, cfg)
+
+	pio.ChainSMs(producer, consumer, dmaChannel)
+	producer.SetEnabled(true)
+
+	for i := uint32(0); ; i++ {
+		for producer.IsTxFIFOFull() {
+		}
+		producer.TxPut(i)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"device/rp"
+	"runtime/volatile"
+	"unsafe"
+)
+
+// Single DMA channel. See rp.DMA_Type.
+type dmaChannel struct {
+	READ_ADDR   volatile.Register32
+	WRITE_ADDR  volatile.Register32
+	TRANS_COUNT volatile.Register32
+	CTRL_TRIG   volatile.Register32
+	_           [12]volatile.Register32 // aliases
+}
+
+// DMA channels usable on the RP2040.
+var dmaChannels = (*[12]dmaChannel)(unsafe.Pointer(rp.DMA))
+
+// dmaReceive starts a DMA transfer of len(dst) words from the register at
+// readAddr into dst, paced by the given DREQ. The transfer runs in the background;
+// use busy to check for completion.
+func (ch *dmaChannel) dmaReceive(dst []uint32, readAddr uintptr, dreq uint32) {
+	ch.READ_ADDR.Set(uint32(readAddr))
+	ch.WRITE_ADDR.Set(uint32(uintptr(unsafe.Pointer(&dst[0]))))
+	ch.TRANS_COUNT.Set(uint32(len(dst)))
+	chainTo := uint32(ch.index()) // Chaining to itself disables chaining.
+	ch.CTRL_TRIG.Set(rp.DMA_CH0_CTRL_TRIG_EN |
+		rp.DMA_CH0_CTRL_TRIG_INCR_WRITE |
+		2<<rp.DMA_CH0_CTRL_TRIG_DATA_SIZE_Pos | // 32 bit transfers.
+		dreq<<rp.DMA_CH0_CTRL_TRIG_TREQ_SEL_Pos |
+		chainTo<<rp.DMA_CH0_CTRL_TRIG_CHAIN_TO_Pos)
+}
+
+func (ch *dmaChannel) busy() bool {
+	return ch.CTRL_TRIG.Get()&rp.DMA_CH0_CTRL_TRIG_BUSY != 0
+}
+
+func (ch *dmaChannel) index() uintptr {
+	return (uintptr(unsafe.Pointer(ch)) - uintptr(unsafe.Pointer(&dmaChannels[0]))) / unsafe.Sizeof(*ch)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"machine"
+	"time"
+	"unsafe"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Capture configuration.
+const (
+	captureBase     = machine.GP16
+	capturePinCount = 2
+	sampleRate      = 1 * machine.MHz
+	sampleCount     = 96
+	dmaChannelIndex = 0
+
+	triggerPin   = 0 // Relative to captureBase.
+	triggerLevel = true
+)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	println("Initializing logic analyzer")
+	sm := pio.PIO0.StateMachine(0)
+	la, err := newLogicAnalyzer(sm, captureBase, capturePinCount, sampleRate)
+	if err != nil {
+		panic(err.Error())
+	}
+	buf := make([]uint32, la.wordsForSamples(sampleCount))
+	for {
+		println("Arming trigger")
+		la.capture(buf, triggerPin, triggerLevel)
+		for dmaChannels[dmaChannelIndex].busy() {
+		}
+		la.print(buf, sampleCount)
+		time.Sleep(time.Second)
+	}
+}
+
+type logicAnalyzer struct {
+	sm       pio.StateMachine
+	offset   uint8
+	pinCount uint8
+	// bitsPerWord is the autopush threshold. It is a multiple of pinCount
+	// so samples never straddle two words.
+	bitsPerWord uint8
+}
+
+func newLogicAnalyzer(sm pio.StateMachine, base machine.Pin, pinCount uint8, sampleHz uint32) (*logicAnalyzer, error) {
+	// The whole program is a single `in pins, pinCount` instruction wrapping onto itself.
+	instructions := []uint16{pio.EncodeIn(pio.SrcDestPins, uint16(pinCount))}
+	offset, err := sm.PIO.AddProgram(instructions, -1)
+	if err != nil {
+		return nil, err
+	}
+	bitsPerWord := 32 - 32%pinCount
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetWrap(offset, offset)
+	cfg.SetInPins(base)
+	cfg.SetInShift(false, true, uint16(bitsPerWord))
+	cfg.SetFIFOJoin(pio.FIFO_JOIN_RX)
+	// Clock divider in 16.8 fixed point.
+	div := uint64(machine.CPUFrequency()) * 256 / uint64(sampleHz)
+	cfg.SetClkDivIntFrac(uint16(div>>8), uint8(div))
+	for i := uint8(0); i < pinCount; i++ {
+		(base + machine.Pin(i)).Configure(machine.PinConfig{Mode: machine.PinPIO0})
+	}
+	sm.Init(offset, cfg)
+	return &logicAnalyzer{
+		sm:          sm,
+		offset:      offset,
+		pinCount:    pinCount,
+		bitsPerWord: bitsPerWord,
+	}, nil
+}
+
+func (la *logicAnalyzer) wordsForSamples(n int) int {
+	samplesPerWord := int(la.bitsPerWord / la.pinCount)
+	return (n + samplesPerWord - 1) / samplesPerWord
+}
+
+// capture arms the DMA and starts sampling once the trigger pin (relative to
+// the capture base) reaches level.
+func (la *logicAnalyzer) capture(buf []uint32, trigPin uint8, level bool) {
+	la.sm.SetEnabled(false)
+	la.sm.ClearFIFOs()
+	la.sm.Restart()
+	la.sm.Exec(pio.EncodeJmp(uint16(la.offset)))
+
+	rxf := &la.sm.PIO.HW.RXF0
+	rxAddr := uintptr(unsafe.Pointer(rxf)) + 4*uintptr(la.sm.StateMachineIndex())
+	dmaChannels[dmaChannelIndex].dmaReceive(buf, rxAddr, la.sm.RxDREQ())
+
+	// The wait instruction is latched while the state machine is stopped and
+	// stalls it once enabled until the trigger condition is met.
+	la.sm.Exec(pio.EncodeWaitPin(level, uint16(trigPin)))
+	la.sm.SetEnabled(true)
+}
+
+// print prints the captured samples as one row of high/low marks per pin.
+func (la *logicAnalyzer) print(buf []uint32, n int) {
+	samplesPerWord := int(la.bitsPerWord / la.pinCount)
+	// With left shifting the first sample ends up in the most significant bits used.
+	for pin := 0; pin < int(la.pinCount); pin++ {
+		print("GP", int(captureBase)+pin, ": ")
+		for sample := 0; sample < n; sample++ {
+			word := buf[sample/samplesPerWord]
+			sampleInWord := sample % samplesPerWord
+			bit := int(la.bitsPerWord) - (sampleInWord+1)*int(la.pinCount) + pin
+			if word&(1<<bit) != 0 {
+				print("-")
+			} else {
+				print("_")
+			}
+		}
+		println()
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Blink an LED by pushing 'set pins' instructions through the TX FIFO to a
+// state machine running the single instruction program pio.OutExecProgram.
+// No program reload is needed to change what the state machine does.
+const ledPin = machine.LED
+
+func main() {
+	time.Sleep(2 * time.Second)
+	sm, err := pio.PIO0.ClaimUnusedStateMachine()
+	if err != nil {
+		panic(err.Error())
+	}
+	sm.PIO.Configure()
+	offset, err := sm.PIO.AddProgram(pio.OutExecProgram.Instructions, pio.OutExecProgram.Origin)
+	if err != nil {
+		panic(err.Error())
+	}
+	cfg := pio.OutExecConfig(offset)
+	cfg.SetSetPins(ledPin, 1)
+	sm.Init(offset, cfg)
+	ledPin.Configure(machine.PinConfig{Mode: sm.PIO.PinMode()})
+	sm.SetConsecutivePinDirs(ledPin, 1, true)
+	sm.SetEnabled(true)
+
+	on := []uint16{pio.EncodeSet(pio.SrcDestPins, 1)}
+	off := []uint16{pio.EncodeSet(pio.SrcDestPins, 0)}
+	for {
+		sm.ExecFromFIFO(on)
+		time.Sleep(500 * time.Millisecond)
+		sm.ExecFromFIFO(off)
+		time.Sleep(500 * time.Millisecond)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"machine"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// ParallelCapture samples a parallel data bus on every rising edge of a pixel
+// clock into a DMA buffer, as needed for OV7670-style cameras and parallel ADCs.
+// It is the receive counterpart of the tufty parallel display output.
+//
+// The program waits on the clock pin, which must be within 32 pins after the
+// data base since 'wait pin' indexes are relative to the 'in' base:
+//
+//	.wrap_target
+//	    wait 1 pin PCLK
+//	    in pins, DATA_BITS
+//	    wait 0 pin PCLK
+//	.wrap
+type ParallelCapture struct {
+	sm     pio.StateMachine
+	offset uint8
+	dmaCh  uint8
+	vsync  machine.Pin
+}
+
+// NewParallelCapture prepares sm to capture dataBits pins starting at dataBase
+// on rising edges of pclk. If vsync is not machine.NoPin every capture starts on
+// the next rising edge of vsync, i.e. at the start of a frame.
+func NewParallelCapture(sm pio.StateMachine, dataBase machine.Pin, dataBits uint8, pclk, vsync machine.Pin, dmaCh uint8) (*ParallelCapture, error) {
+	if dataBits == 0 || dataBits > 32 {
+		return nil, errors.New("parallelcapture: data bits out of range 1..32")
+	}
+	pclkIndex := uint16(pclk-dataBase) & 0x1f
+	program := &pio.Program{
+		Instructions: []uint16{
+			pio.EncodeWaitPin(true, pclkIndex),
+			pio.EncodeIn(pio.SrcDestPins, uint16(dataBits)),
+			pio.EncodeWaitPin(false, pclkIndex),
+		},
+		Origin: -1,
+		Wrap:   2,
+	}
+	offset, err := sm.PIO.AddProgram(program.Instructions, program.Origin)
+	if err != nil {
+		return nil, err
+	}
+	cfg := program.DefaultConfig(offset)
+	cfg.SetInPins(dataBase)
+	// Shifting right places the first sample in the least significant bits,
+	// so the buffer read as bytes is in sample order for 8 bit buses.
+	cfg.SetInShift(true, true, uint16(32-32%dataBits))
+	cfg.SetFIFOJoin(pio.FIFO_JOIN_RX)
+	mode := sm.PIO.PinMode()
+	for i := uint8(0); i < dataBits; i++ {
+		(dataBase + machine.Pin(i)).Configure(machine.PinConfig{Mode: mode})
+	}
+	pclk.Configure(machine.PinConfig{Mode: mode})
+	sm.Init(offset, cfg)
+	return &ParallelCapture{sm: sm, offset: offset, dmaCh: dmaCh, vsync: vsync}, nil
+}
+
+// Capture fills buf with samples and blocks until it is full.
+func (pc *ParallelCapture) Capture(buf []uint32) {
+	pc.sm.SetEnabled(false)
+	pc.sm.ClearFIFOs()
+	pc.sm.Restart()
+	pc.sm.Exec(pio.EncodeJmp(uint16(pc.offset)))
+	pio.StartCaptureWords(pc.sm, pc.dmaCh, buf)
+	if pc.vsync != machine.NoPin {
+		// A forced wait is latched while stopped and stalls the state machine
+		// once enabled until the frame starts.
+		pc.sm.Exec(pio.EncodeWaitGPIO(true, uint16(pc.vsync)))
+	}
+	pc.sm.SetEnabled(true)
+	for pio.DMABusy(pc.dmaCh) {
+	}
+	pc.sm.SetEnabled(false)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Capture 8-bit samples from a parallel bus clocked by an external pixel clock.
+const (
+	dataBase = machine.GP0 // D0..D7 on GP0..GP7.
+	pclkPin  = machine.GP8
+	vsyncPin = machine.GP9
+	dmaCh    = 0
+)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	sm, err := pio.PIO0.ClaimUnusedStateMachine()
+	if err != nil {
+		panic(err.Error())
+	}
+	sm.PIO.Configure()
+	pc, err := NewParallelCapture(sm, dataBase, 8, pclkPin, vsyncPin, dmaCh)
+	if err != nil {
+		panic(err.Error())
+	}
+	words := make([]uint32, 256)
+	samples := make([]byte, 4*len(words))
+	for {
+		pc.Capture(words)
+		pio.UnpackBytes(samples, words, true)
+		print("first samples:")
+		for _, b := range samples[:16] {
+			print(" ", b)
+		}
+		println()
+		time.Sleep(time.Second)
+	}
+}
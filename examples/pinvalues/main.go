@@ -0,0 +1,49 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Generate a slow square wave and print the level the pin actually has, to
+// confirm the state machine is driving it.
+const (
+	wavePin = machine.GP15
+	freqHz  = 2000
+)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	sm, err := pio.PIO0.ClaimUnusedStateMachine()
+	if err != nil {
+		panic(err.Error())
+	}
+	sm.PIO.Configure()
+	prog, cfg, err := pio.GenerateSquareWave(freqHz)
+	if err != nil {
+		panic(err.Error())
+	}
+	offset, err := sm.PIO.AddProgram(prog.Instructions, prog.Origin)
+	if err != nil {
+		panic(err.Error())
+	}
+	if err := sm.InitSideSetPins(wavePin, 1); err != nil {
+		panic(err.Error())
+	}
+	cfg.SetSidePins(wavePin)
+	sm.Init(offset, cfg)
+	sm.SetEnabled(true)
+
+	for {
+		var highs int
+		const samples = 1000
+		for i := 0; i < samples; i++ {
+			highs += int(pio.CurrentPinValues(wavePin, 1))
+		}
+		// A running square wave reads high about half of the time.
+		println("pin high in", highs, "of", samples, "samples")
+		time.Sleep(time.Second)
+	}
+}
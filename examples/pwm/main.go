@@ -0,0 +1,27 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	pwm, err := NewPWM(pio.PIO0.StateMachine(0), machine.LED, 1000)
+	if err != nil {
+		panic(err.Error())
+	}
+	// Fade the LED in and out.
+	for {
+		for i := 0; i <= 100; i++ {
+			pwm.SetDuty(float32(i) / 100)
+			time.Sleep(10 * time.Millisecond)
+		}
+		for i := 100; i >= 0; i-- {
+			pwm.SetDuty(float32(i) / 100)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
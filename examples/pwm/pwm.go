@@ -0,0 +1,98 @@
+package main
+
+import (
+	"machine"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// pwmInstructions is the pico-examples PWM program. The compare level is held
+// in X so `pull noblock` falls back to it when no new level was written, and
+// the period is held in ISR.
+//
+//	.side_set 1 opt
+//	.wrap_target
+//	    pull noblock    side 0 ; Pull a new level into OSR if available, else copy X.
+//	    mov x, osr             ; Keep the most recent level in X.
+//	    mov y, isr             ; ISR holds the period. Y is the loop counter.
+//	countloop:
+//	    jmp x!=y noset         ; Set pin high once Y reaches the level.
+//	    jmp skip        side 1
+//	noset:
+//	    nop                    ; Keep both paths the same length.
+//	skip:
+//	    jmp y-- countloop
+//	.wrap
+var pwmInstructions = []uint16{
+	0x9080, //  0: pull   noblock         side 0
+	0xa027, //  1: mov    x, osr
+	0xa046, //  2: mov    y, isr
+	0x00a5, //  3: jmp    x != y, 5
+	0x1806, //  4: jmp    6               side 1
+	0xa042, //  5: nop
+	0x0083, //  6: jmp    y--, 3
+}
+
+const (
+	pwmWrapTarget = 0
+	pwmWrap       = 6
+)
+
+// pwmPeriod is the number of duty cycle steps per PWM period.
+const pwmPeriod = 1000
+
+// cyclesPerPeriod is the number of state machine cycles in one PWM period:
+// 3 cycles per count plus 3 cycles of setup.
+const cyclesPerPeriod = 3*(pwmPeriod+1) + 3
+
+// PWM generates a pulse width modulated signal with a state machine, for
+// use when all hardware PWM slices are taken.
+type PWM struct {
+	sm pio.StateMachine
+}
+
+// NewPWM loads the PWM program and starts outputting a 0% duty cycle
+// signal of freqHz on pin.
+func NewPWM(sm pio.StateMachine, pin machine.Pin, freqHz uint32) (*PWM, error) {
+	if err := sm.InitSideSetPins(pin, 1); err != nil {
+		return nil, err
+	}
+	offset, err := sm.PIO.AddProgram(pwmInstructions, -1)
+	if err != nil {
+		return nil, err
+	}
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetWrap(offset+pwmWrapTarget, offset+pwmWrap)
+	cfg.SetSideSet(2, true, false)
+	cfg.SetSidePins(pin)
+	sm.Init(offset, cfg)
+
+	// Seed the period in ISR and a zero level in X before starting.
+	sm.TxPut(pwmPeriod)
+	sm.Exec(pio.EncodePull(false, false))
+	sm.Exec(pio.EncodeOut(pio.SrcDestISR, 32))
+	sm.SetScratchX(0)
+
+	p := &PWM{sm: sm}
+	p.SetFrequency(freqHz)
+	sm.SetEnabled(true)
+	return p, nil
+}
+
+// SetFrequency changes the PWM frequency by retuning the clock divider.
+func (p *PWM) SetFrequency(hz uint32) {
+	p.sm.RecomputeClkDiv(hz * cyclesPerPeriod)
+}
+
+// SetDuty sets the fraction of the period the output is high, between 0 and 1.
+// The new level is written to the TX FIFO and picked up by the program at
+// the start of the next period, so updates never glitch the output.
+func (p *PWM) SetDuty(fraction float32) {
+	switch {
+	case fraction < 0:
+		fraction = 0
+	case fraction > 1:
+		fraction = 1
+	}
+	p.sm.TxPutBlocking(uint32(fraction * pwmPeriod))
+}
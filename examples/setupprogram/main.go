@@ -0,0 +1,41 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Output square waves of different frequencies on two pins, letting
+// SetupProgram pick a free state machine and program offset for each.
+var waves = []struct {
+	pin    machine.Pin
+	freqHz uint32
+}{
+	{machine.GP15, 1000},
+	{machine.GP16, 2500},
+}
+
+func main() {
+	time.Sleep(2 * time.Second)
+	block := pio.PIO0
+	block.Configure()
+	for _, w := range waves {
+		prog, cfg, err := pio.GenerateSquareWave(w.freqHz)
+		if err != nil {
+			panic(err.Error())
+		}
+		cfg.SetSidePins(w.pin)
+		sm, offset, err := block.SetupProgram(prog, cfg)
+		if err != nil {
+			panic(err.Error())
+		}
+		if err := sm.InitSideSetPins(w.pin, 1); err != nil {
+			panic(err.Error())
+		}
+		sm.SetEnabled(true)
+		println("pin", w.pin, "on state machine", sm.StateMachineIndex(), "program at offset", offset)
+	}
+	select {}
+}
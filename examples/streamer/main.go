@@ -0,0 +1,60 @@
+package main
+
+import (
+	"device/rp"
+	"machine"
+	"runtime/interrupt"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+const dataPin = machine.GP15
+
+// Shift each word out on a single pin, LSB first:
+//
+//	.wrap_target
+//	    out pins, 1
+//	.wrap
+var serialInstructions = []uint16{
+	pio.EncodeOut(pio.SrcDestPins, 1),
+}
+
+var sm = pio.PIO0.StateMachine(0)
+
+var streamer = pio.NewStreamer(sm, 0)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	offset, err := sm.PIO.AddProgram(serialInstructions, -1)
+	if err != nil {
+		panic(err.Error())
+	}
+	cfg := pio.DefaultStateMachineConfig()
+	if err := sm.ConfigureOutPins(&cfg, dataPin, 1); err != nil {
+		panic(err.Error())
+	}
+	cfg.SetWrap(offset, offset)
+	cfg.SetOutShift(true, true, pio.ShiftThresholdFull)
+	cfg.SetClkDivIntFrac(10000, 0) // Slow enough to see the CPU sleep between refills.
+	sm.Init(offset, cfg)
+	sm.SetEnabled(true)
+
+	intr := interrupt.New(rp.IRQ_PIO0_IRQ_0, func(interrupt.Interrupt) {
+		streamer.HandleInterrupt()
+	})
+	intr.Enable()
+
+	data := make([]uint32, 64)
+	for i := range data {
+		data[i] = 0xAAAA_0000 | uint32(i)
+	}
+	for {
+		streamer.Start(data)
+		for streamer.Busy() {
+			time.Sleep(time.Millisecond)
+		}
+		println("stream done")
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Output the same square wave from PIO0 and PIO1 and align their phase with
+// pio.SyncClkDiv. Probe both pins with a logic analyzer to see the residual skew.
+const (
+	pin0   = machine.GP0
+	pin1   = machine.GP1
+	freqHz = 1000000
+)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	sm0 := pio.PIO0.StateMachine(0)
+	sm1 := pio.PIO1.StateMachine(0)
+	initSquareWave(sm0, pin0)
+	initSquareWave(sm1, pin1)
+
+	sm0.Resume()
+	sm1.Resume()
+	pio.SyncClkDiv(sm0, sm1)
+	for {
+		time.Sleep(time.Second)
+	}
+}
+
+func initSquareWave(sm pio.StateMachine, pin machine.Pin) {
+	sm.PIO.Configure()
+	prog, cfg, err := pio.GenerateSquareWave(freqHz)
+	if err != nil {
+		panic(err.Error())
+	}
+	offset, err := sm.PIO.AddProgram(prog.Instructions, prog.Origin)
+	if err != nil {
+		panic(err.Error())
+	}
+	if err := sm.InitSideSetPins(pin, 1); err != nil {
+		panic(err.Error())
+	}
+	cfg.SetSidePins(pin)
+	sm.Init(offset, cfg)
+	achieved, err := pio.SquareWaveFrequency(freqHz)
+	if err != nil {
+		panic(err.Error())
+	}
+	println("square wave at", achieved, "Hz")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+const buzzerPin = machine.GP15
+
+const (
+	noteC4 = 262
+	noteD4 = 294
+	noteE4 = 330
+	noteF4 = 349
+	noteG4 = 392
+	noteA4 = 440
+	noteB4 = 494
+	noteC5 = 523
+)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	tone, err := NewTone(pio.PIO0.StateMachine(0), buzzerPin)
+	if err != nil {
+		panic(err.Error())
+	}
+	const beat = 250 * time.Millisecond
+	scale := []Note{
+		{noteC4, beat}, {noteD4, beat}, {noteE4, beat}, {noteF4, beat},
+		{noteG4, beat}, {noteA4, beat}, {noteB4, beat}, {noteC5, 2 * beat},
+		{0, beat},
+	}
+	for {
+		tone.PlayMelody(scale)
+		time.Sleep(time.Second)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// cyclesPerPeriod is the number of state machine cycles per square wave period
+// of toneInstructions. The long delays bring audible frequencies within reach
+// of the 16-bit clock divider.
+const cyclesPerPeriod = 64
+
+// toneInstructions toggles a pin with a 50% duty cycle:
+//
+//	.wrap_target
+//	    set pins, 1 [31]
+//	    set pins, 0 [31]
+//	.wrap
+var toneInstructions = []uint16{
+	pio.EncodeSet(pio.SrcDestPins, 1) | pio.EncodeDelay(cyclesPerPeriod/2-1),
+	pio.EncodeSet(pio.SrcDestPins, 0) | pio.EncodeDelay(cyclesPerPeriod/2-1),
+}
+
+// Note is a tone of a given frequency and duration. A Freq of 0 is a rest.
+type Note struct {
+	Freq     uint32
+	Duration time.Duration
+}
+
+// Tone generates square waves on a pin, typically to drive a piezo buzzer.
+type Tone struct {
+	sm  pio.StateMachine
+	pin machine.Pin
+}
+
+// NewTone loads the tone program and configures sm to output on pin.
+func NewTone(sm pio.StateMachine, pin machine.Pin) (*Tone, error) {
+	offset, err := sm.PIO.AddProgram(toneInstructions, -1)
+	if err != nil {
+		return nil, err
+	}
+	pin.Configure(machine.PinConfig{Mode: sm.PIO.PinMode()})
+	sm.SetConsecutivePinDirs(pin, 1, true)
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetWrap(offset, offset+uint8(len(toneInstructions))-1)
+	cfg.SetSetPins(pin, 1)
+	sm.Init(offset, cfg)
+	return &Tone{sm: sm, pin: pin}, nil
+}
+
+// Play starts outputting a square wave of freqHz. The divider is retuned on the
+// fly so Play may be called while a tone is already playing.
+func (t *Tone) Play(freqHz uint32) {
+	if freqHz == 0 {
+		t.Stop()
+		return
+	}
+	t.sm.RecomputeClkDiv(freqHz * cyclesPerPeriod)
+	t.sm.SetEnabled(true)
+}
+
+// Stop stops the tone and drives the pin low so the buzzer does not draw current.
+func (t *Tone) Stop() {
+	t.sm.SetEnabled(false)
+	t.sm.SetPinsWithMask(0, 1<<t.pin)
+}
+
+// PlayMelody plays notes in sequence, blocking until the melody is done.
+func (t *Tone) PlayMelody(notes []Note) {
+	for _, note := range notes {
+		t.Play(note.Freq)
+		time.Sleep(note.Duration)
+	}
+	t.Stop()
+}
@@ -41,12 +41,29 @@ type ST7789 struct {
 // ParallelInit initializes everything necessary to communicate with the display
 // using an 8-bit parallel connection
 func (st *ST7789) ParallelInit() {
-	offset, err := st.pio.AddProgram(st7789_parallelInstructions, st7789_parallelOrigin)
+	program := &pio.Program{
+		Instructions: st7789_parallelInstructions,
+		Origin:       st7789_parallelOrigin,
+		WrapTarget:   st7789_parallelWrapTarget,
+		Wrap:         st7789_parallelWrap,
+		SideSetCount: 1,
+	}
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetSideSet(1, false, false)
+	cfg.SetOutPins(st.d0, 8)
+	cfg.SetSidePins(st.wr)
+	cfg.SetFIFOJoin(pio.FIFO_JOIN_TX)
+	cfg.SetByteOutput(true)
+	maxPIOClk := uint32(32 * machine.MHz)
+	clkDiv := (machine.CPUFrequency() + maxPIOClk - 1) / maxPIOClk
+	cfg.SetClkDivIntFrac(uint16(clkDiv), 1)
+
+	driver, err := st.pio.NewDriver(program, cfg, st.stateMachineIndex)
 	if err != nil {
 		panic(err.Error())
 	}
-	sm := st.pio.StateMachine(st.stateMachineIndex)
-	parallelST7789Init(sm, offset, st.d0, st.wr)
+	st.parallelOffset = uint32(driver.Offset())
+	driver.Start()
 }
 
 func (st *ST7789) SetBacklight(on bool) {
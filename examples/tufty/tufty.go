@@ -40,6 +40,7 @@ func main() {
 
 	println("Initializing PIO")
 	display.pio = pio.PIO0
+	display.pio.Configure()
 	println("Parallel Init")
 	display.ParallelInit()
 
@@ -48,7 +49,7 @@ func main() {
 	dmaConfig := getDefaultDMAConfig(display.dmaChannel)
 	setTransferDataSize(dmaConfig, DMA_SIZE_8)
 	setBSwap(dmaConfig, false)
-	setDREQ(dmaConfig, display.pio.HW.GetIRQ())
+	setDREQ(dmaConfig, display.pio.StateMachine(display.stateMachineIndex).TxDREQ())
 	dmaChannelConfigure(display.dmaChannel, dmaConfig, display.pio.HW.TXF0.Reg, 0, 0, false)
 
 	rdPin.High()
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Drive a 640x480 60Hz monochrome VGA signal with three state machines of PIO0:
+//
+//   - hsync generates the 800 pixel line and raises IRQ 0 at the start of the visible area.
+//   - vsync counts lines on IRQ 0, generates the 525 line frame and raises IRQ 1
+//     at the start of every visible line.
+//   - pixel waits for IRQ 1 and shifts out 640 pixels, fed continuously by a DMA
+//     ring streaming the framebuffer.
+//
+// hsync and vsync run at the 25MHz pixel clock and pixel at 125MHz, 5 cycles per
+// pixel, so the system clock must be 125MHz. The three clock dividers are
+// restarted together with pio.SyncClkDiv after the state machines are enabled on
+// the same cycle, which keeps the line handshake deterministic.
+//
+// Pin assignments, connect the video pin to the R, G and B inputs through a 330 ohm
+// resistor each and the sync pins through 47 ohm resistors:
+const (
+	hsyncPin = machine.GP16 // VGA pin 13.
+	vsyncPin = machine.GP17 // VGA pin 14.
+	videoPin = machine.GP18 // VGA pins 1, 2 and 3.
+)
+
+const (
+	width  = 640
+	height = 480
+	// DMA channels used for the framebuffer ring.
+	dataChannel = 0
+	ctrlChannel = 1
+)
+
+// hsync generates one line: 656 cycles visible area and front porch, 96 cycles
+// sync pulse and 48 cycles back porch ending in IRQ 0.
+//
+//	    pull block            ; x reload value, 655
+//	.wrap_target
+//	    mov x, osr
+//	active:
+//	    jmp x-- active
+//	    set pins, 0 [31]      ; sync pulse
+//	    set pins, 0 [31]
+//	    set pins, 0 [31]
+//	    set pins, 1 [31]      ; back porch
+//	    set pins, 1 [12]
+//	    irq 0       [1]
+//	.wrap
+var hsyncProgram = &pio.Program{
+	Instructions: []uint16{0x80a0, 0xa027, 0x0042, 0xff00, 0xff00, 0xff00, 0xff01, 0xec01, 0xc100},
+	Origin:       -1,
+	WrapTarget:   1,
+	Wrap:         8,
+}
+
+// vsync counts lines with IRQ 0: 480 visible lines signalled to the pixel
+// state machine with IRQ 1, 10 lines front porch, 2 lines sync pulse and
+// 33 lines back porch.
+//
+//	    pull block            ; x reload value, 479
+//	.wrap_target
+//	    mov x, osr
+//	active:
+//	    wait 1 irq 0
+//	    irq 1
+//	    jmp x-- active
+//	    set y, 9
+//	front:
+//	    wait 1 irq 0
+//	    jmp y-- front
+//	    set pins, 0           ; sync pulse
+//	    wait 1 irq 0
+//	    wait 1 irq 0
+//	    set pins, 1
+//	    set y, 31
+//	back:
+//	    wait 1 irq 0
+//	    jmp y-- back
+//	    wait 1 irq 0
+//	.wrap
+var vsyncProgram = &pio.Program{
+	Instructions: []uint16{
+		0x80a0, 0xa027, 0x20c0, 0xc001, 0x0042, 0xe049, 0x20c0, 0x0086,
+		0xe000, 0x20c0, 0x20c0, 0xe001, 0xe05f, 0x20c0, 0x008d, 0x20c0,
+	},
+	Origin:     -1,
+	WrapTarget: 1,
+	Wrap:       15,
+}
+
+// pixel shifts out one line of pixels, MSB first, after each IRQ 1.
+//
+//	    pull block            ; pixels per line minus one, 639
+//	    out y, 32
+//	.wrap_target
+//	    set pins, 0           ; black during blanking
+//	    mov x, y
+//	    wait 1 irq 1 [3]
+//	pixels:
+//	    out pins, 1 [3]
+//	    jmp x-- pixels
+//	.wrap
+var pixelProgram = &pio.Program{
+	Instructions: []uint16{0x80a0, 0x6040, 0xe000, 0xa022, 0x23c1, 0x6301, 0x0045},
+	Origin:       -1,
+	WrapTarget:   2,
+	Wrap:         6,
+}
+
+// framebuffer holds one bit per pixel, the leftmost pixel of every 32 in the MSB.
+var framebuffer [width * height / 32]uint32
+
+func main() {
+	time.Sleep(2 * time.Second)
+	drawTestPattern()
+	pio.PIO0.Configure()
+
+	hsync := newSyncDriver(hsyncProgram, hsyncPin, 0)
+	vsync := newSyncDriver(vsyncProgram, vsyncPin, 1)
+
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetSetPins(videoPin, 1)
+	cfg.SetOutPins(videoPin, 1)
+	cfg.SetOutShift(false, true, pio.ShiftThresholdFull)
+	cfg.SetFIFOJoin(pio.FIFO_JOIN_TX)
+	pixel, err := pio.PIO0.NewDriver(pixelProgram, cfg, 2)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	// Counter reload values, pulled once by each program before its loop.
+	hsync.StateMachine().TxPut(800 - 144 - 1)
+	vsync.StateMachine().TxPut(height - 1)
+	pixel.StateMachine().TxPut(width - 1)
+	if _, err := pixel.StateMachine().StreamRing(framebuffer[:], dataChannel, ctrlChannel); err != nil {
+		panic(err.Error())
+	}
+
+	pio.PIO0.SetEnabledMask(0b111)
+	pio.SyncClkDiv(hsync.StateMachine(), vsync.StateMachine(), pixel.StateMachine())
+	println("VGA running")
+	for {
+		time.Sleep(time.Second)
+	}
+}
+
+// newSyncDriver loads a sync program driving pin at the 25MHz pixel clock.
+func newSyncDriver(program *pio.Program, pin machine.Pin, smIndex uint8) *pio.SMDriver {
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetSetPins(pin, 1)
+	cfg.SetClkDivIntFrac(5, 0)
+	d, err := pio.PIO0.NewDriver(program, cfg, smIndex)
+	if err != nil {
+		panic(err.Error())
+	}
+	return d
+}
+
+// drawTestPattern draws a one pixel border and a checkerboard of 32x32 squares.
+func drawTestPattern() {
+	const wordsPerLine = width / 32
+	for y := 0; y < height; y++ {
+		for x := 0; x < wordsPerLine; x++ {
+			var word uint32
+			if (x+y/32)%2 == 0 {
+				word = 0xffffffff
+			}
+			switch {
+			case y == 0 || y == height-1:
+				word = 0xffffffff
+			case x == 0:
+				word |= 1 << 31
+			case x == wordsPerLine-1:
+				word |= 1
+			}
+			framebuffer[y*wordsPerLine+x] = word
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"machine"
+	"time"
+
+	pio "github.com/soypat/rp2040-pio"
+)
+
+// Output a 16-bit counting pattern on GP0..GP15 using two state machines in
+// lockstep, each driving 8 of the pins with the program:
+//
+//	.wrap_target
+//	    out pins, 8
+//	.wrap
+const (
+	basePin  = machine.GP0
+	lanePins = 8
+	lanes    = 2
+	sampleHz = 1000000
+)
+
+func main() {
+	time.Sleep(2 * time.Second)
+	block := pio.PIO0
+	block.Configure()
+	prog := pio.ProgramFromHex(-1, pio.EncodeOut(pio.SrcDestPins, lanePins))
+	cfg := pio.DefaultStateMachineConfig()
+	cfg.SetWordOutput(false) // Shift right: the first sample is the least significant byte.
+	div := machine.CPUFrequency() / sampleHz
+	cfg.SetClkDivIntFrac(uint16(div), 0)
+	wide, err := block.ConfigureWideOutput(prog, cfg, basePin, lanePins, lanes)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	var samples [64]uint16
+	for i := range samples {
+		samples[i] = uint16(i) * 0x0101
+	}
+	words := packLanes(samples[:])
+	wide.Start()
+	for {
+		if err := wide.Write(words); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// packLanes splits 16-bit samples into the low byte lane 0 drives and the high
+// byte lane 1 drives, 4 samples per word, interleaving the words for
+// WideOutput.Write. len(samples) must be a multiple of 4.
+func packLanes(samples []uint16) []uint32 {
+	words := make([]uint32, 0, len(samples)/2)
+	for i := 0; i < len(samples); i += 4 {
+		var lo, hi uint32
+		for j := 0; j < 4; j++ {
+			lo |= uint32(samples[i+j]&0xff) << (8 * j)
+			hi |= uint32(samples[i+j]>>8) << (8 * j)
+		}
+		words = append(words, lo, hi)
+	}
+	return words
+}
@@ -0,0 +1,39 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+// OutExecProgram is a single instruction program that executes every
+// instruction written to the TX FIFO:
+//
+//	.wrap_target
+//	    out exec, 16
+//	.wrap
+//
+// Configure the state machine with OutExecConfig and feed it with ExecFromFIFO.
+// This allows running dynamic command sequences without reloading program memory.
+var OutExecProgram = &Program{
+	Instructions: []uint16{EncodeOutExec()},
+	Origin:       -1,
+}
+
+// OutExecConfig returns the state machine configuration for OutExecProgram
+// loaded at offset. The OSR shifts right with autopull at 16 bits so each
+// FIFO word carries one instruction in its low half and the upper half is discarded.
+func OutExecConfig(offset uint8) StateMachineConfig {
+	cfg := OutExecProgram.DefaultConfig(offset)
+	cfg.SetOutShift(true, true, 16)
+	return cfg
+}
+
+// ExecFromFIFO pushes the encoded instructions into the TX FIFO, one per word,
+// to be executed by a state machine running OutExecProgram or an equivalent
+// 'out exec, 16' loop. It blocks while the FIFO is full.
+//
+// A jump executed this way moves the program counter out of the exec loop.
+// Side-set and delay bits are interpreted per the state machine's configuration.
+func (sm StateMachine) ExecFromFIFO(instrs []uint16) {
+	for _, instr := range instrs {
+		sm.TxPutBlocking(uint32(instr))
+	}
+}
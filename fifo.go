@@ -0,0 +1,12 @@
+package pio
+
+// DecodeFIFOLevels splits an FLEVEL register value into per state machine TX
+// and RX FIFO levels. Each state machine has a TX and an RX nibble, with the TX
+// nibble of state machine n at bit 8n and its RX nibble at bit 8n+4.
+func DecodeFIFOLevels(flevel uint32) (tx [4]uint8, rx [4]uint8) {
+	for i := range tx {
+		tx[i] = uint8(flevel>>(8*i)) & 0xf
+		rx[i] = uint8(flevel>>(8*i+4)) & 0xf
+	}
+	return tx, rx
+}
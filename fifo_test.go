@@ -0,0 +1,16 @@
+package pio
+
+import "testing"
+
+func TestDecodeFIFOLevels(t *testing.T) {
+	// SM0 TX 1 RX 2, SM1 TX 8 (joined) RX 0, SM2 TX 0 RX 8, SM3 TX 4 RX 4.
+	tx, rx := DecodeFIFOLevels(0x44_80_08_21)
+	wantTx := [4]uint8{1, 8, 0, 4}
+	wantRx := [4]uint8{2, 0, 8, 4}
+	if tx != wantTx || rx != wantRx {
+		t.Errorf("DecodeFIFOLevels = tx %v rx %v, want tx %v rx %v", tx, rx, wantTx, wantRx)
+	}
+	if tx, rx := DecodeFIFOLevels(0); tx != [4]uint8{} || rx != [4]uint8{} {
+		t.Errorf("DecodeFIFOLevels(0) = tx %v rx %v, want all empty", tx, rx)
+	}
+}
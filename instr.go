@@ -24,12 +24,19 @@ const (
 	SrcDestY       SrcDest = 2
 	SrcDestNull    SrcDest = 3
 	SrcDestPinDirs SrcDest = 4
+	// SrcDestExecMov is the 'mov exec, src' destination: the moved value is
+	// executed as an instruction on the next cycle.
 	SrcDestExecMov SrcDest = 4
 	SrcDestStatus  SrcDest = 5
 	SrcDestPC      SrcDest = 5
 	SrcDestISR     SrcDest = 6
 	SrcDestOSR     SrcDest = 7
-	SrcExecOut     SrcDest = 7
+	// SrcExecOut is the 'out exec, n' destination: the shifted out bits are
+	// executed as an instruction on the next cycle.
+	SrcExecOut SrcDest = 7
+
+	// DestExec aliases SrcDestExecMov for readability in 'mov exec' encodings.
+	DestExec = SrcDestExecMov
 )
 
 func MajorInstrBits(instr uint16) uint16 {
@@ -52,6 +59,28 @@ func EncodeSideSet(bitCount uint16, value uint16) uint16 {
 	return value << (13 - bitCount)
 }
 
+// EncodeDelayChecked is like EncodeDelay but returns ErrDelayTooLarge if cycles
+// does not fit in the delay bits left over by sideSetBits side-set bits
+// (including the enable bit of an optional side-set). See DelaySideSetBudget.
+func EncodeDelayChecked(cycles uint16, sideSetBits uint8) (uint16, error) {
+	if sideSetBits > 5 {
+		return 0, ErrSideSetBudgetExceeded
+	}
+	if cycles >= 1<<(5-sideSetBits) {
+		return 0, ErrDelayTooLarge
+	}
+	return EncodeDelay(cycles), nil
+}
+
+// EncodeSideSetChecked is like EncodeSideSet but returns ErrSideSetBudgetExceeded
+// if bitCount exceeds the 5-bit delay/side-set field or value does not fit in bitCount bits.
+func EncodeSideSetChecked(bitCount uint16, value uint16) (uint16, error) {
+	if bitCount > 5 || value >= 1<<bitCount {
+		return 0, ErrSideSetBudgetExceeded
+	}
+	return EncodeSideSet(bitCount, value), nil
+}
+
 func EncodeSetSetOpt(bitCount uint16, value uint16) uint16 {
 	return 0x1000 | value<<(12-bitCount)
 }
@@ -105,6 +134,27 @@ func EncodeOut(dest SrcDest, value uint16) uint16 {
 	return EncodeInstrAndSrcDest(INSTR_BITS_OUT, dest, value)
 }
 
+// EncodeOutExec encodes 'out exec, 16', which executes the next 16 bits of the
+// OSR as an instruction. See OutExecProgram.
+//
+// The executed instruction runs in place of the next instruction of the program:
+// a jump moves the program counter and a stalling instruction such as 'wait'
+// stalls the program. Delay cycles of the executed instruction apply as usual
+// but those of the 'out exec' itself are ignored.
+func EncodeOutExec() uint16 {
+	return EncodeOut(SrcExecOut, 16)
+}
+
+// EncodeMovExec encodes 'mov exec, src', which executes the value of src
+// (usually x, y or osr) as an instruction. It has the same hazards as EncodeOutExec.
+func EncodeMovExec(src SrcDest) uint16 {
+	return EncodeMov(SrcDestExecMov, src)
+}
+
+// EncodePush encodes 'push [iffull] [block|noblock]'. ifFull sets bit 6 so the
+// push only happens once the ISR has reached the push threshold; block sets bit 5
+// so the state machine stalls while the RX FIFO is full, otherwise the push is
+// dropped when the FIFO is full.
 func EncodePush(ifFull bool, block bool) uint16 {
 	arg := uint16(0)
 	if ifFull {
@@ -117,6 +167,10 @@ func EncodePush(ifFull bool, block bool) uint16 {
 	return EncodeInstrAndArgs(INSTR_BITS_PUSH, arg, 0)
 }
 
+// EncodePull encodes 'pull [ifempty] [block|noblock]'. ifEmpty sets bit 6 so the
+// pull only happens once the OSR has reached the pull threshold; block sets bit 5
+// so the state machine stalls while the TX FIFO is empty, otherwise a pull from
+// an empty FIFO copies the X register into the OSR.
 func EncodePull(ifEmpty bool, block bool) uint16 {
 	arg := uint16(0)
 	if ifEmpty {
@@ -149,10 +203,21 @@ func EncodeIRQClear(relative bool, irq uint16) uint16 {
 	return EncodeInstrAndArgs(INSTR_BITS_IRQ, 2, EncodeIRQ(relative, irq))
 }
 
+// EncodeSet encodes a 'set' instruction. SET only carries a 5-bit immediate so
+// value is truncated to its low 5 bits. Use EncodeSetChecked to catch values above 31.
 func EncodeSet(dest SrcDest, value uint16) uint16 {
 	return EncodeInstrAndSrcDest(INSTR_BITS_SET, dest, value)
 }
 
+// EncodeSetChecked is like EncodeSet but returns an error if value does not
+// fit in the 5-bit immediate (0..31) instead of truncating it.
+func EncodeSetChecked(dest SrcDest, value uint16) (uint16, error) {
+	if value > 0x1f {
+		return 0, ErrSetValueOutOfRange
+	}
+	return EncodeSet(dest, value), nil
+}
+
 func EncodeNOP() uint16 {
 	return EncodeMov(SrcDestY, SrcDestY)
 }
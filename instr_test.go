@@ -0,0 +1,45 @@
+package pio
+
+import "testing"
+
+func TestEncodePushPull(t *testing.T) {
+	// Encodings from the RP2040 datasheet: bit 6 is IfFull/IfEmpty, bit 5 Block.
+	tests := []struct {
+		cond, block bool
+		push, pull  uint16
+	}{
+		{false, false, 0x8000, 0x8080},
+		{false, true, 0x8020, 0x80a0},
+		{true, false, 0x8040, 0x80c0},
+		{true, true, 0x8060, 0x80e0},
+	}
+	for _, tt := range tests {
+		if got := EncodePush(tt.cond, tt.block); got != tt.push {
+			t.Errorf("EncodePush(%v, %v) = %#04x, want %#04x", tt.cond, tt.block, got, tt.push)
+		}
+		if got := EncodePull(tt.cond, tt.block); got != tt.pull {
+			t.Errorf("EncodePull(%v, %v) = %#04x, want %#04x", tt.cond, tt.block, got, tt.pull)
+		}
+	}
+}
+
+func TestEncodeExec(t *testing.T) {
+	tests := []struct {
+		instr uint16
+		want  uint16
+		asm   string
+	}{
+		{EncodeOutExec(), 0x60f0, "out exec, 16"},
+		{EncodeMovExec(SrcDestX), 0xa081, "mov exec, x"},
+		{EncodeMovExec(SrcDestOSR), 0xa087, "mov exec, osr"},
+		{EncodeMov(DestExec, SrcDestY), 0xa082, "mov exec, y"},
+	}
+	for _, tt := range tests {
+		if tt.instr != tt.want {
+			t.Errorf("%s encoded as %#04x, want %#04x", tt.asm, tt.instr, tt.want)
+		}
+		if got := DisassembleInstruction(tt.instr, 0, false); got != tt.asm {
+			t.Errorf("DisassembleInstruction(%#04x) = %q, want %q", tt.instr, got, tt.asm)
+		}
+	}
+}
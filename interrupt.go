@@ -0,0 +1,38 @@
+package pio
+
+import "math/bits"
+
+// InterruptKind is the kind of a per state machine PIO interrupt source.
+// Each kind occupies a group of four bits, one per state machine, in the
+// INTR, INTE, INTF and INTS registers.
+type InterruptKind uint8
+
+const (
+	// InterruptRxNotEmpty is asserted while the state machine's RX FIFO is not empty.
+	InterruptRxNotEmpty InterruptKind = iota
+	// InterruptTxNotFull is asserted while the state machine's TX FIFO is not full.
+	InterruptTxNotFull
+	// InterruptSM is asserted while PIO IRQ flag n is set, for state machine index n.
+	// Only IRQ flags 0..3 can be routed to system interrupts.
+	InterruptSM
+)
+
+// DecodeInterruptSource translates the lowest set bit of an INTR, INTE, INTF
+// or INTS register value into the state machine index and kind of the
+// interrupt source. ok is false if no valid source bit is set.
+//
+// To decode all sources clear the lowest set bit after each call:
+//
+//	for ints != 0 {
+//		sm, kind, _ := pio.DecodeInterruptSource(ints)
+//		ints &= ints - 1
+//		...
+//	}
+func DecodeInterruptSource(ints uint32) (sm uint8, kind InterruptKind, ok bool) {
+	ints &= 0xfff // Only 12 sources exist.
+	if ints == 0 {
+		return 0, 0, false
+	}
+	bit := uint8(bits.TrailingZeros32(ints))
+	return bit % 4, InterruptKind(bit / 4), true
+}
@@ -0,0 +1,40 @@
+package pio
+
+import "testing"
+
+func TestDecodeInterruptSource(t *testing.T) {
+	tests := []struct {
+		ints   uint32
+		sm     uint8
+		kind   InterruptKind
+		wantOk bool
+	}{
+		{0, 0, 0, false},
+		{1 << 0, 0, InterruptRxNotEmpty, true},
+		{1 << 3, 3, InterruptRxNotEmpty, true},
+		{1 << 5, 1, InterruptTxNotFull, true},
+		{1 << 10, 2, InterruptSM, true},
+		{1 << 11, 3, InterruptSM, true},
+		{1<<6 | 1<<9, 2, InterruptTxNotFull, true}, // Lowest bit wins.
+		{1 << 12, 0, 0, false},                     // Not a source.
+		{0xfffff000 | 1<<4, 0, InterruptTxNotFull, true},
+	}
+	for _, tt := range tests {
+		sm, kind, ok := DecodeInterruptSource(tt.ints)
+		if ok != tt.wantOk || ok && (sm != tt.sm || kind != tt.kind) {
+			t.Errorf("DecodeInterruptSource(%#x) = %d, %d, %v; want %d, %d, %v", tt.ints, sm, kind, ok, tt.sm, tt.kind, tt.wantOk)
+		}
+	}
+	// Clearing the lowest bit after each call visits every source once.
+	n := 0
+	for ints := uint32(0xfff); ints != 0; ints &= ints - 1 {
+		sm, kind, ok := DecodeInterruptSource(ints)
+		if !ok || uint32(kind)*4+uint32(sm) != uint32(n) {
+			t.Fatalf("source %d decoded as sm %d kind %d", n, sm, kind)
+		}
+		n++
+	}
+	if n != 12 {
+		t.Errorf("decoded %d sources, want 12", n)
+	}
+}
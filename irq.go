@@ -0,0 +1,61 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"runtime/volatile"
+	"unsafe"
+)
+
+// irqHW are the interrupt registers of one of the two PIO system interrupt lines.
+type irqHW struct {
+	INTE volatile.Register32
+	INTF volatile.Register32
+	INTS volatile.Register32
+}
+
+// irqHW returns the interrupt registers for PIO system interrupt line 0 or 1.
+func (pio *PIO) irqHW(irqLine uint8) *irqHW {
+	switch irqLine {
+	case 0:
+		return (*irqHW)(unsafe.Pointer(&pio.HW.IRQ0_INTE))
+	case 1:
+		return (*irqHW)(unsafe.Pointer(&pio.HW.IRQ1_INTE))
+	}
+	panic("invalid PIO IRQ line")
+}
+
+// interruptBit returns the bit of the interrupt source of kind for this state machine.
+func (sm StateMachine) interruptBit(kind InterruptKind) uint32 {
+	return 1 << (4*uint32(kind) + uint32(sm.index))
+}
+
+// SetInterruptEnabled enables or disables routing of this state machine's
+// interrupt source of the given kind to PIO system interrupt line irqLine (0 or 1).
+// The corresponding NVIC interrupt (e.g. rp.IRQ_PIO0_IRQ_0) must be enabled separately.
+func (sm StateMachine) SetInterruptEnabled(irqLine uint8, kind InterruptKind, enabled bool) {
+	inte := &sm.PIO.irqHW(irqLine).INTE
+	if enabled {
+		AliasRegister(inte, AliasSet).Set(sm.interruptBit(kind))
+	} else {
+		AliasRegister(inte, AliasClear).Set(sm.interruptBit(kind))
+	}
+}
+
+// InterruptStatus returns the INTS register of PIO system interrupt line
+// irqLine (0 or 1): the interrupt sources that are both asserted and enabled,
+// or forced. Decode it with DecodeInterruptSource.
+//
+// INTS is computed regardless of the NVIC, so sources can be enabled with
+// SetInterruptEnabled and polled, e.g. between tasks of a cooperative
+// scheduler, without installing an interrupt handler.
+func (pio *PIO) InterruptStatus(irqLine uint8) uint32 {
+	return pio.irqHW(irqLine).INTS.Get()
+}
+
+// RawInterruptStatus returns the INTR register: the interrupt sources that are
+// asserted, whether or not they are enabled on either interrupt line.
+func (pio *PIO) RawInterruptStatus() uint32 {
+	return pio.HW.INTR.Get()
+}
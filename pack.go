@@ -0,0 +1,90 @@
+package pio
+
+import "math/bits"
+
+// PackBytesMSB packs src into dst so that a state machine configured to shift
+// out to the left (SetOutShift with shiftRight=false) emits the bytes in order.
+// The first byte of each group of four is placed in the most significant byte
+// of the word. A trailing partial word is left aligned.
+//
+// dst must have room for at least (len(src)+3)/4 words.
+func PackBytesMSB(dst []uint32, src []byte) {
+	if len(dst) < (len(src)+3)/4 {
+		panic("pio: PackBytesMSB dst too short")
+	}
+	for i, b := range src {
+		if i%4 == 0 {
+			dst[i/4] = 0
+		}
+		dst[i/4] |= uint32(b) << (24 - 8*(i%4))
+	}
+}
+
+// PackBytesLSB packs src into dst so that a state machine configured to shift
+// out to the right (SetOutShift with shiftRight=true) emits the bytes in order.
+// The first byte of each group of four is placed in the least significant byte
+// of the word, which matches the RP2040's little endian memory layout.
+// A trailing partial word is right aligned.
+//
+// dst must have room for at least (len(src)+3)/4 words.
+func PackBytesLSB(dst []uint32, src []byte) {
+	if len(dst) < (len(src)+3)/4 {
+		panic("pio: PackBytesLSB dst too short")
+	}
+	for i, b := range src {
+		if i%4 == 0 {
+			dst[i/4] = 0
+		}
+		dst[i/4] |= uint32(b) << (8 * (i % 4))
+	}
+}
+
+// PackBytes packs src into dst in the order a state machine with autopull at
+// 32 bits shifts them out, given the OUT shift direction of SetOutShift, and
+// returns the number of words written. See PackBytesMSB and PackBytesLSB.
+func PackBytes(dst []uint32, src []byte, shiftRight bool) int {
+	if shiftRight {
+		PackBytesLSB(dst, src)
+	} else {
+		PackBytesMSB(dst, src)
+	}
+	return (len(src) + 3) / 4
+}
+
+// UnpackBytes is the inverse of PackBytes for data received with autopush at
+// 32 bits: it extracts the bytes of src into dst in the order they were shifted
+// in, given the IN shift direction of SetInShift. Shifting left puts the first
+// byte in the most significant byte of the word, shifting right in the least
+// significant byte. It returns the number of bytes written, limited by len(dst).
+func UnpackBytes(dst []byte, src []uint32, shiftRight bool) int {
+	n := 4 * len(src)
+	if len(dst) < n {
+		n = len(dst)
+	}
+	for i := 0; i < n; i++ {
+		shift := 24 - 8*(i%4)
+		if shiftRight {
+			shift = 8 * (i % 4)
+		}
+		dst[i] = byte(src[i/4] >> shift)
+	}
+	return n
+}
+
+// ReverseBits returns the n least significant bits of v in reverse order.
+// Bits above n are discarded. n is clamped to 32.
+//
+// Use it when the shift direction of the state machine does not match the bit
+// order of the wire protocol. For example, WS2812 expects 24-bit GRB data MSB first:
+// with SetOutShift(false, true, 24) the color is sent left aligned (v<<8), whereas
+// a program shifting right, SetOutShift(true, true, 24), emits the LSB first and
+// needs ReverseBits(grb, 24) to get the colors right.
+func ReverseBits(v uint32, n uint8) uint32 {
+	if n == 0 {
+		return 0
+	}
+	if n > 32 {
+		n = 32
+	}
+	return bits.Reverse32(v) >> (32 - n)
+}
@@ -5,8 +5,9 @@ package pio
 
 import (
 	"device/rp"
-	"errors"
 	"machine"
+	"math/bits"
+	"runtime/interrupt"
 	"runtime/volatile"
 	"unsafe"
 )
@@ -21,16 +22,14 @@ var (
 	}
 )
 
-// PIO errors.
-var (
-	ErrOutOfProgramSpace = errors.New("pio: out of program space")
-	ErrNoSpaceAtOffset   = errors.New("pio: program space unavailable at offset")
-)
-
 // PIO represents one of the two PIO peripherals in the RP2040
+//
+// Program allocation and state machine claiming methods are safe for
+// concurrent use by multiple goroutines on both cores. They must not be called
+// from interrupt handlers.
 type PIO struct {
-	// Bitmask of used instruction space
-	usedSpaceMask uint32
+	// Program allocation and claim state.
+	allocator
 	// HW is the actual hardware device
 	HW *rp.PIO0_Type
 }
@@ -42,6 +41,12 @@ type StateMachine struct {
 
 	// index of this state machine
 	index uint8
+
+	// Register pointers cached by PIO.StateMachine so hot paths such as
+	// TxPut and Exec skip the pointer arithmetic.
+	hw    *statemachineHW
+	txReg *volatile.Register32
+	rxReg *volatile.Register32
 }
 
 // StateMachineIndex returns the index of the state machine within the PIO.
@@ -60,15 +65,66 @@ func (pio *PIO) BlockIndex() uint8 {
 	panic("invalid PIO")
 }
 
+// Configure enables the PIO block by releasing it from reset in the RESETS
+// register and waiting until the reset is done. The PIO blocks are clocked
+// directly from clk_sys so there is no separate clock to enable.
+//
+// The TinyGo runtime usually releases the PIO blocks from reset during startup,
+// in which case this is a no-op. Configure is idempotent and leaves a running
+// block untouched, so it is always safe to call before using the block,
+// including after Deinit.
+func (pio *PIO) Configure() {
+	resetBit := pio.resetBit()
+	if !rp.RESETS.RESET.HasBits(resetBit) && rp.RESETS.RESET_DONE.HasBits(resetBit) {
+		return // Already out of reset.
+	}
+	rp.RESETS.RESET.ClearBits(resetBit)
+	for !rp.RESETS.RESET_DONE.HasBits(resetBit) {
+	}
+}
+
+// Deinit holds the PIO block in reset through the RESETS register, stopping
+// all state machines and returning every register and the instruction memory to
+// their reset state, which lowers power use while the block is unused. Program
+// space and state machine claims are released. Call Configure to use the block again.
+func (pio *PIO) Deinit() {
+	pio.lock()
+	defer pio.unlock()
+	rp.RESETS.RESET.SetBits(pio.resetBit())
+	pio.releasePrograms()
+	pio.claimedMask = 0
+}
+
+// resetBit returns this PIO block's bit in the RESETS registers.
+func (pio *PIO) resetBit() uint32 {
+	if pio.BlockIndex() == 1 {
+		return rp.RESETS_RESET_PIO1
+	}
+	return rp.RESETS_RESET_PIO0
+}
+
 // StateMachine returns a state machine by index.
 func (pio *PIO) StateMachine(index uint8) StateMachine {
 	if index > 3 {
 		panic("invalid state machine index")
 	}
-	return StateMachine{
+	sm := StateMachine{
 		PIO:   pio,
 		index: index,
 	}
+	sm.hw = pio.smHW(index)
+	sm.txReg = sm.fifoReg(&pio.HW.TXF0)
+	sm.rxReg = sm.fifoReg(&pio.HW.RXF0)
+	return sm
+}
+
+// StateMachineChecked is like StateMachine but returns ErrInvalidSMIndex
+// instead of panicking if index is not in 0..3.
+func (pio *PIO) StateMachineChecked(index uint8) (StateMachine, error) {
+	if index > 3 {
+		return StateMachine{}, ErrInvalidSMIndex
+	}
+	return pio.StateMachine(index), nil
 }
 
 // AddProgram loads a PIO program into PIO memory and returns the offset where it was loaded.
@@ -79,51 +135,101 @@ func (pio *PIO) StateMachine(index uint8) StateMachine {
 // origin indicates where in the PIO execution memory the program must be loaded,
 // or -1 if the code is position independent.
 func (pio *PIO) AddProgram(instructions []uint16, origin int8) (uint8, error) {
-	offset := pio.findOffsetForProgram(instructions, origin)
-	if offset < 0 {
-		return 0, ErrOutOfProgramSpace
+	pio.lock()
+	defer pio.unlock()
+	return pio.allocator.addProgram(pio, instructions, origin)
+}
+
+// AddProgramPreferring loads program at preferredOffset if there is space
+// there, otherwise it falls back to the same search as AddProgram. It returns
+// the offset where the program was loaded. This is useful to keep interacting
+// programs contiguous or at known addresses.
+func (pio *PIO) AddProgramPreferring(program *Program, preferredOffset uint8) (uint8, error) {
+	pio.lock()
+	defer pio.unlock()
+	if pio.allocator.addProgramAtOffset(pio, program.Instructions, program.Origin, preferredOffset) == nil {
+		return preferredOffset, nil
 	}
-	pio.AddProgramAtOffset(instructions, origin, uint8(offset))
-	return uint8(offset), nil
+	return pio.allocator.addProgram(pio, program.Instructions, program.Origin)
 }
 
 // AddProgramAtOffset loads a PIO program into PIO memory at a specific offset
 // and returns a non-nil error if there is not enough space.
 func (pio *PIO) AddProgramAtOffset(instructions []uint16, origin int8, offset uint8) error {
-	if !pio.CanAddProgramAtOffset(instructions, origin, offset) {
-		return ErrNoSpaceAtOffset
+	pio.lock()
+	defer pio.unlock()
+	return pio.allocator.addProgramAtOffset(pio, instructions, origin, offset)
+}
+
+// RemoveProgram frees the instruction space of the program loaded at offset
+// so it can be reused. The instruction memory itself is not modified.
+func (pio *PIO) RemoveProgram(offset uint8) error {
+	pio.lock()
+	defer pio.unlock()
+	if !pio.release(offset) {
+		return ErrProgramNotLoaded
 	}
+	return nil
+}
 
-	programLen := uint8(len(instructions))
-	for i := uint8(0); i < programLen; i++ {
-		instr := instructions[i]
+// UsedSpaceMask returns a bitmask of the instruction memory slots in use,
+// bit n being set if slot n is occupied by a loaded program.
+func (pio *PIO) UsedSpaceMask() uint32 {
+	pio.lock()
+	defer pio.unlock()
+	return pio.usedSpaceMask
+}
 
-		// Patch jump instructions with relative offset
-		if INSTR_BITS_JMP == instr&INSTR_BITS_Msk {
-			pio.writeInstructionMemory(offset+i, instr+uint16(offset))
-		} else {
-			pio.writeInstructionMemory(offset+i, instr)
+// FreeInstructionSlots returns the number of unused instruction memory slots.
+// The free slots may not be contiguous.
+func (pio *PIO) FreeInstructionSlots() uint8 {
+	return uint8(32 - bits.OnesCount32(pio.UsedSpaceMask()))
+}
+
+// LoadedProgram describes a program occupying PIO instruction memory.
+type LoadedProgram struct {
+	Offset uint8
+	Length uint8
+}
+
+// LoadedPrograms returns the programs currently loaded in instruction memory
+// ordered by offset.
+func (pio *PIO) LoadedPrograms() []LoadedProgram {
+	pio.lock()
+	defer pio.unlock()
+	var loaded []LoadedProgram
+	for offset, length := range pio.programLengths {
+		if length != 0 {
+			loaded = append(loaded, LoadedProgram{Offset: uint8(offset), Length: length})
 		}
 	}
+	return loaded
+}
 
-	// Mark the instruction space as in-use
-	programMask := uint32((1 << programLen) - 1)
-	pio.usedSpaceMask |= programMask << uint32(offset)
-	return nil
+// ReplaceProgramAtOffset overwrites the program old loaded at offset with
+// newProg in place. Both programs must have the same length. The used instruction
+// space is left unchanged so other loaded programs are not disturbed.
+//
+// State machines executing the old program will continue at the same program
+// counter in the new one, so callers should usually halt them first.
+func (pio *PIO) ReplaceProgramAtOffset(old, newProg *Program, offset uint8) error {
+	pio.lock()
+	defer pio.unlock()
+	return pio.allocator.replaceProgramAtOffset(pio, old, newProg, offset)
 }
 
 // CanAddProgramAtOffset returns true if there is enough space for program at given offset.
 func (pio *PIO) CanAddProgramAtOffset(instructions []uint16, origin int8, offset uint8) bool {
-	// Non-relocatable programs must be added at offset
-	if origin >= 0 && origin != int8(offset) {
-		return false
-	}
-
-	programMask := uint32((1 << len(instructions)) - 1)
-	return pio.usedSpaceMask&(programMask<<offset) == 0
+	pio.lock()
+	defer pio.unlock()
+	return pio.canAddProgramAtOffset(instructions, origin, offset)
 }
 
 func (pio *PIO) writeInstructionMemory(offset uint8, value uint16) {
+	if offset > 31 {
+		// Writing past INSTR_MEM31 would clobber state machine registers.
+		panic("pio: instruction memory offset out of range")
+	}
 	// Instead of using MEM0, MEM1, etc, calculate the offset of the
 	// disired register starting at MEM0
 	start := unsafe.Pointer(&pio.HW.INSTR_MEM0)
@@ -133,31 +239,44 @@ func (pio *PIO) writeInstructionMemory(offset uint8, value uint16) {
 	reg.Set(uint32(value))
 }
 
-func (pio *PIO) findOffsetForProgram(instructions []uint16, origin int8) int8 {
-	programLen := uint32(len(instructions))
-	programMask := uint32((1 << programLen) - 1)
-
-	// Program has fixed offset (not relocatable)
-	if origin >= 0 {
-		if uint32(origin) > 32-programLen {
-			return -1
-		}
-
-		if (pio.usedSpaceMask & (programMask << origin)) != 0 {
-			return -1
-		}
-
-		return origin
+// WaitIRQ busy-waits until the PIO IRQ flag at index (0..7) is set by a state
+// machine and then clears it. This is the CPU side of an `irq set` or `irq wait`
+// instruction in a PIO program.
+func (pio *PIO) WaitIRQ(index uint8) {
+	if index > 7 {
+		panic("invalid IRQ index")
+	}
+	mask := uint32(1) << index
+	for pio.HW.IRQ.Get()&mask == 0 {
 	}
+	// IRQ flags are cleared by writing a 1 to them.
+	pio.HW.IRQ.Set(mask)
+}
 
-	// work down from the top always
-	for i := int8(32 - programLen); i >= 0; i-- {
-		if pio.usedSpaceMask&(programMask<<uint32(i)) == 0 {
-			return i
-		}
+// ForceIRQ sets the PIO IRQ flag at index (0..7) from the CPU by writing IRQ_FORCE.
+//
+// The effect is the same as a state machine executing `irq set index`: state
+// machines waiting on the flag and enabled system interrupts see it
+// raised. Unlike a program-raised IRQ no state machine is involved, which makes
+// it useful to exercise CPU-side IRQ handling before the PIO program exists.
+func (pio *PIO) ForceIRQ(index uint8) {
+	if index > 7 {
+		panic("invalid IRQ index")
 	}
+	pio.HW.IRQ_FORCE.Set(1 << index)
+}
 
-	return -1
+// EnabledMask returns a bitmask of the enabled state machines in this PIO block,
+// bit n being set if state machine n is running.
+func (pio *PIO) EnabledMask() uint32 {
+	return ctrlEnabledMask(pio.HW.CTRL.Get())
+}
+
+// SetEnabledMask enables the state machines whose bit is set in mask and
+// disables the rest in a single register write, so that they start or stop
+// on the same clock cycle. It can be used to restore a mask returned by EnabledMask.
+func (pio *PIO) SetEnabledMask(mask uint32) {
+	pio.HW.CTRL.Set(ctrlWithEnabledMask(pio.HW.CTRL.Get(), mask))
 }
 
 // Init initializes the state machine
@@ -166,6 +285,22 @@ func (pio *PIO) findOffsetForProgram(instructions []uint16, origin int8) int8 {
 // cfg is optional.  If the zero value of StateMachineConfig is used
 // then the default configuration is used.
 func (sm StateMachine) Init(initialPC uint8, cfg StateMachineConfig) {
+	sm.InitWithOptions(initialPC, cfg, InitOptions{})
+}
+
+// InitOptions modifies the behaviour of InitWithOptions. The zero value
+// initializes the state machine exactly like Init.
+type InitOptions struct {
+	// KeepFIFOs leaves queued FIFO data in place instead of clearing both
+	// FIFOs, so a streaming state machine can be reconfigured on the fly, e.g.
+	// to tweak its clock divider or wrap, without dropping pending output.
+	// The hardware still discards the FIFO contents if cfg changes the FIFO join,
+	// and the restart still discards partially shifted data in the ISR and OSR.
+	KeepFIFOs bool
+}
+
+// InitWithOptions is like Init with the behaviour adjusted by opts.
+func (sm StateMachine) InitWithOptions(initialPC uint8, cfg StateMachineConfig, opts InitOptions) {
 	// Halt the state machine to set sensible defaults
 	sm.SetEnabled(false)
 	sm.IsTxFIFOEmpty()
@@ -176,18 +311,83 @@ func (sm StateMachine) Init(initialPC uint8, cfg StateMachineConfig) {
 		sm.SetConfig(cfg)
 	}
 
+	if !opts.KeepFIFOs {
+		sm.ClearFIFOs()
+	}
+	sm.clearFIFODebugFlags()
+
+	sm.Restart()
+	sm.ClkDivRestart()
+	sm.Exec(EncodeJmp(uint16(initialPC)))
+}
+
+// InitGlitchFree is like Init but keeps the side-set pins at sideSetLevel,
+// with the side-set base pin in bit 0, throughout initialization.
+//
+// The jump to initialPC forced by Init carries a side-set value of 0, so with a
+// mandatory side-set it drives the side-set pins low for a moment, which can be
+// a spurious edge on a clock or chip select line. InitGlitchFree applies the
+// configuration, forces a 'nop' side-setting sideSetLevel and then forces the
+// jump with the same side-set value, so the pins never see another level.
+// The side-set pins should already be outputs, see InitSideSetPins.
+func (sm StateMachine) InitGlitchFree(initialPC uint8, cfg StateMachineConfig, sideSetLevel uint8) {
+	if cfg == (StateMachineConfig{}) {
+		cfg = DefaultStateMachineConfig()
+	}
+	sm.SetEnabled(false)
+	sm.SetConfig(cfg)
+
+	var sideSetField uint16
+	_, _, sideSet := cfg.pinGroups()
+	switch {
+	case sideSet.count == 0:
+	case cfg.ExecCtrl&rp.PIO0_SM0_EXECCTRL_SIDE_EN != 0:
+		sideSetField = EncodeSetSetOpt(uint16(sideSet.count), uint16(sideSetLevel))
+	default:
+		sideSetField = EncodeSideSet(uint16(sideSet.count), uint16(sideSetLevel))
+	}
+	sm.Exec(EncodeNOP() | sideSetField)
+
 	sm.ClearFIFOs()
+	sm.clearFIFODebugFlags()
+	sm.Restart()
+	sm.ClkDivRestart()
+	sm.Exec(EncodeJmp(uint16(initialPC)) | sideSetField)
+}
 
-	// Clear FIFO debug flags
+// clearFIFODebugFlags clears the sticky FDEBUG flags of this state machine.
+func (sm StateMachine) clearFIFODebugFlags() {
 	fdebugMask := uint32((1 << rp.PIO0_FDEBUG_TXOVER_Pos) |
 		(1 << rp.PIO0_FDEBUG_RXUNDER_Pos) |
 		(1 << rp.PIO0_FDEBUG_TXSTALL_Pos) |
 		(1 << rp.PIO0_FDEBUG_RXSTALL_Pos))
 	sm.PIO.HW.FDEBUG.Set(fdebugMask << sm.index)
+}
 
-	sm.Restart()
-	sm.ClkDivRestart()
-	sm.Exec(EncodeJmp(uint16(initialPC)))
+// Stop disables the state machine, discards the contents of both its FIFOs
+// and clears its FIFO debug flags. The configuration is left untouched.
+//
+// Output pins keep the last value driven by the state machine; use
+// SetPinsWithMask afterwards to park them in a known state.
+func (sm StateMachine) Stop() {
+	sm.SetEnabled(false)
+	sm.ClearFIFOs()
+	sm.clearFIFODebugFlags()
+}
+
+// TeardownAll stops all four state machines and clears the instruction memory,
+// releasing all program space.
+func (pio *PIO) TeardownAll() {
+	for i := uint8(0); i < 4; i++ {
+		pio.StateMachine(i).Stop()
+	}
+	pio.lock()
+	defer pio.unlock()
+	for i := uint8(0); i < 32; i++ {
+		// Jump to self, as done by the c-sdk.
+		pio.writeInstructionMemory(i, EncodeJmp(uint16(i)))
+	}
+	pio.releasePrograms()
 }
 
 // SetEnabled controls whether the state machine is running
@@ -195,6 +395,57 @@ func (sm StateMachine) SetEnabled(enabled bool) {
 	sm.PIO.HW.CTRL.ReplaceBits(boolToBit(enabled), 0x1, sm.index)
 }
 
+// Enabled returns true if the state machine is running.
+func (sm StateMachine) Enabled() bool {
+	return ctrlEnabled(sm.PIO.HW.CTRL.Get(), sm.index)
+}
+
+// SyncClkDiv restarts the clock dividers of sms with a phase of 0 as close
+// together as possible, so state machines with equal dividers tick in lockstep.
+// It also works for state machines spread across PIO0 and PIO1.
+//
+// Within a block all dividers restart on the same cycle. The two blocks have
+// separate CTRL registers, so PIO1's dividers restart one bus write after PIO0's,
+// typically a single system clock cycle later. True zero skew across blocks is not
+// achievable; keep lanes that must be exactly aligned on the same block.
+func SyncClkDiv(sms ...StateMachine) {
+	var masks [2]uint32
+	var blocks [2]*PIO
+	for _, sm := range sms {
+		i := sm.PIO.BlockIndex()
+		blocks[i] = sm.PIO
+		masks[i] |= 1 << (rp.PIO0_CTRL_CLKDIV_RESTART_Pos + uint32(sm.index))
+	}
+	// Compute both register addresses ahead so the writes are back to back.
+	var ctrl [2]*volatile.Register32
+	for i, block := range blocks {
+		if block != nil {
+			ctrl[i] = AliasRegister(&block.HW.CTRL, AliasSet)
+		}
+	}
+	for i := range ctrl {
+		if ctrl[i] != nil {
+			ctrl[i].Set(masks[i])
+		}
+	}
+}
+
+// Pause halts the state machine without touching its configuration, FIFOs,
+// scratch registers or program counter. Resume continues execution from the
+// halted instruction. Unlike Stop and Init, nothing is cleared, so Pause and
+// Resume can be used to momentarily hold a running program.
+//
+// Only this state machine's enable bit is cleared, with an atomic register
+// alias, so other state machines of the block are unaffected.
+func (sm StateMachine) Pause() {
+	AliasRegister(&sm.PIO.HW.CTRL, AliasClear).Set(1 << (rp.PIO0_CTRL_SM_ENABLE_Pos + uint32(sm.index)))
+}
+
+// Resume re-enables a state machine halted with Pause.
+func (sm StateMachine) Resume() {
+	AliasRegister(&sm.PIO.HW.CTRL, AliasSet).Set(1 << (rp.PIO0_CTRL_SM_ENABLE_Pos + uint32(sm.index)))
+}
+
 // Restart restarts the state machine
 func (sm StateMachine) Restart() {
 	sm.PIO.HW.CTRL.SetBits(1 << (rp.PIO0_CTRL_SM_RESTART_Pos + sm.index))
@@ -205,7 +456,29 @@ func (sm StateMachine) ClkDivRestart() {
 	sm.PIO.HW.CTRL.SetBits(1 << (rp.PIO0_CTRL_CLKDIV_RESTART_Pos + sm.index))
 }
 
+// SetClkDivIntFrac sets the clock divider of a running state machine from a
+// whole and fractional part. It takes effect immediately.
+func (sm StateMachine) SetClkDivIntFrac(div uint16, frac uint8) {
+	sm.HW().CLKDIV.Set((uint32(frac) << rp.PIO0_SM0_CLKDIV_FRAC_Pos) |
+		(uint32(div) << rp.PIO0_SM0_CLKDIV_INT_Pos))
+}
+
+// RecomputeClkDiv reads the current system clock frequency and reprograms the
+// clock divider of the state machine so that it runs as close as possible to targetHz.
+//
+// Clock dividers are computed from the CPU frequency at configuration time, so
+// this must be called after any change to the system clock or the state machine
+// will silently run at a different frequency.
+func (sm StateMachine) RecomputeClkDiv(targetHz uint32) {
+	sm.SetClkDivIntFrac(clkDivForFreq(machine.CPUFrequency(), targetHz))
+}
+
 // SetConfig applies state machine configuration to a state machine
+//
+// Output pins are not reset by a new configuration: the last value driven by
+// 'out' or 'set' persists, and with OUT_STICKY (see SetOutSpecial) it keeps being
+// re-asserted. When replacing a sticky configuration, use ReleaseStickyOutput
+// to drive the pins to a known state first.
 func (sm StateMachine) SetConfig(cfg StateMachineConfig) {
 	hw := sm.HW()
 	hw.CLKDIV.Set(cfg.ClkDiv)
@@ -214,21 +487,230 @@ func (sm StateMachine) SetConfig(cfg StateMachineConfig) {
 	hw.PINCTRL.Set(cfg.PinCtrl)
 }
 
+// GetConfig returns the configuration currently applied to the state machine.
+func (sm StateMachine) GetConfig() StateMachineConfig {
+	hw := sm.HW()
+	return StateMachineConfig{
+		ClkDiv:    hw.CLKDIV.Get(),
+		ExecCtrl:  hw.EXECCTRL.Get(),
+		ShiftCtrl: hw.SHIFTCTRL.Get(),
+		PinCtrl:   hw.PINCTRL.Get(),
+	}
+}
+
+// Snapshot captures the CLKDIV, EXECCTRL, SHIFTCTRL and PINCTRL registers, in
+// that order, with interrupts disabled so the four values are consistent.
+// Pass it to Restore to return the state machine to exactly this configuration,
+// e.g. after temporarily repurposing it for a one-off calibration.
+//
+// Program counter, FIFOs and scratch registers are not part of the snapshot.
+func (sm StateMachine) Snapshot() [4]uint32 {
+	state := interrupt.Disable()
+	cfg := sm.GetConfig()
+	interrupt.Restore(state)
+	return [4]uint32{cfg.ClkDiv, cfg.ExecCtrl, cfg.ShiftCtrl, cfg.PinCtrl}
+}
+
+// Restore writes back registers captured with Snapshot, with interrupts disabled.
+func (sm StateMachine) Restore(snap [4]uint32) {
+	state := interrupt.Disable()
+	sm.SetConfig(StateMachineConfig{
+		ClkDiv:    snap[0],
+		ExecCtrl:  snap[1],
+		ShiftCtrl: snap[2],
+		PinCtrl:   snap[3],
+	})
+	interrupt.Restore(state)
+}
+
+// SetSideSetPindirs controls whether side-set drives pin directions (true)
+// or pin values (false) on a live state machine. The side-set enable bit is left untouched.
+func (sm StateMachine) SetSideSetPindirs(pindirs bool) {
+	sm.HW().EXECCTRL.ReplaceBits(boolToBit(pindirs), 0x1, rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR_Pos)
+}
+
+// SideSetControlsPindirs reports whether side-set drives pin directions rather
+// than pin values on the live state machine. A side-set clock that never toggles
+// is often due to side-set accidentally configured for pin directions.
+func (sm StateMachine) SideSetControlsPindirs() bool {
+	return sm.HW().EXECCTRL.HasBits(rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR)
+}
+
 // tx gets a pointer to the TX FIFO register for this state machine.
 func (sm StateMachine) tx() *volatile.Register32 {
-	start := unsafe.Pointer(&sm.PIO.HW.TXF0)
-	offset := uintptr(sm.index) * 4
-	return (*volatile.Register32)(unsafe.Pointer(uintptr(start) + offset))
+	if sm.txReg != nil {
+		return sm.txReg
+	}
+	return sm.fifoReg(&sm.PIO.HW.TXF0)
 }
 
 // rx gets a pointer to the RX FIFO register for this state machine.
 func (sm StateMachine) rx() *volatile.Register32 {
-	start := unsafe.Pointer(&sm.PIO.HW.RXF0)
+	if sm.rxReg != nil {
+		return sm.rxReg
+	}
+	return sm.fifoReg(&sm.PIO.HW.RXF0)
+}
+
+// fifoReg returns this state machine's register in the FIFO register array starting at fifo0.
+func (sm StateMachine) fifoReg(fifo0 *volatile.Register32) *volatile.Register32 {
 	offset := uintptr(sm.index) * 4
-	return (*volatile.Register32)(unsafe.Pointer(uintptr(start) + offset))
+	return (*volatile.Register32)(unsafe.Pointer(uintptr(unsafe.Pointer(fifo0)) + offset))
+}
+
+// TxWriter returns the TX FIFO register of the state machine for tight output
+// loops. Fetch it once and write to it directly to skip the pointer computation
+// and call overhead of TxPut on every word:
+//
+//	tx := sm.TxWriter()
+//	for _, v := range data {
+//		for sm.IsTxFIFOFull() {
+//		}
+//		tx.Set(v)
+//	}
+//
+// As with TxPut, writes to a full FIFO are dropped and set the TXOVER flag.
+func (sm StateMachine) TxWriter() *volatile.Register32 {
+	return sm.tx()
+}
+
+// TxDREQ returns the DMA data request number that paces transfers
+// into this state machine's TX FIFO.
+func (sm StateMachine) TxDREQ() uint32 {
+	return DREQ_PIO0_TX0 + uint32(sm.PIO.BlockIndex())*(DREQ_PIO1_TX0-DREQ_PIO0_TX0) + uint32(sm.index)
+}
+
+// RxDREQ returns the DMA data request number that paces transfers
+// out of this state machine's RX FIFO.
+func (sm StateMachine) RxDREQ() uint32 {
+	return DREQ_PIO0_RX0 + uint32(sm.PIO.BlockIndex())*(DREQ_PIO1_RX0-DREQ_PIO0_RX0) + uint32(sm.index)
+}
+
+// PinMode returns the GPIO function that routes a pin to this PIO block,
+// either machine.PinPIO0 or machine.PinPIO1.
+func (pio *PIO) PinMode() machine.PinMode {
+	if pio.BlockIndex() == 1 {
+		return machine.PinPIO1
+	}
+	return machine.PinPIO0
 }
 
-// SetConsecurityPinDirs sets a range of pins to either 'in' or 'out'
+// PIOForPin returns the PIO block the pin is currently routed to, read from
+// the pin's GPIO function select, or false if the pin is not assigned to a PIO.
+// Drivers can use it to catch a pin configured for the wrong PIO block, which
+// otherwise shows up as a silent lack of output.
+func PIOForPin(pin machine.Pin) (*PIO, bool) {
+	if pin > 29 {
+		return nil, false
+	}
+	switch (gpioCtrl(pin).Get() & rp.IO_BANK0_GPIO0_CTRL_FUNCSEL_Msk) >> rp.IO_BANK0_GPIO0_CTRL_FUNCSEL_Pos {
+	case rp.IO_BANK0_GPIO0_CTRL_FUNCSEL_PIO0_0:
+		return PIO0, true
+	case rp.IO_BANK0_GPIO0_CTRL_FUNCSEL_PIO1_0:
+		return PIO1, true
+	}
+	return nil, false
+}
+
+// gpioCtrl returns the IO_BANK0 GPIOn_CTRL register of pin.
+func gpioCtrl(pin machine.Pin) *volatile.Register32 {
+	// GPIOn_STATUS and GPIOn_CTRL pairs are laid out consecutively, 8 bytes per pin.
+	return (*volatile.Register32)(unsafe.Pointer(uintptr(unsafe.Pointer(&rp.IO_BANK0.GPIO0_CTRL)) + uintptr(pin)*8))
+}
+
+// ConfigurePinsForOutput forces count consecutive pins starting at base to be
+// outputs with the GPIO output enable override, so no instructions are executed
+// on a state machine. The PIO pin directions then have no effect on these pins,
+// which suits the common case of a driver whose pins are outputs for its whole life.
+//
+// The pins must already be routed to a PIO block, e.g. with
+// pin.Configure(machine.PinConfig{Mode: pio.PinMode()}), and must not be
+// reconfigured afterwards: machine.Pin.Configure rewrites the GPIO control
+// register and clears the override. ErrPinNotRouted is returned if a pin is not
+// routed to a PIO block and ErrPinOutOfRange if the pins run past GPIO 29; in
+// both cases no pin is changed.
+//
+// Use SetConsecutivePinDirs instead when the program itself changes pin
+// directions, or to change them on a running state machine. Pins used only as
+// inputs need no direction setup, as PIO pin directions reset to input.
+func ConfigurePinsForOutput(base machine.Pin, count uint8) error {
+	if err := (PinRange{Base: base, Count: count}).Validate(); err != nil {
+		return err
+	}
+	for i := uint8(0); i < count; i++ {
+		if _, ok := PIOForPin(base + machine.Pin(i)); !ok {
+			return ErrPinNotRouted
+		}
+	}
+	for i := uint8(0); i < count; i++ {
+		gpioCtrl(base+machine.Pin(i)).ReplaceBits(rp.IO_BANK0_GPIO0_CTRL_OEOVER_ENABLE,
+			rp.IO_BANK0_GPIO0_CTRL_OEOVER_Msk>>rp.IO_BANK0_GPIO0_CTRL_OEOVER_Pos, rp.IO_BANK0_GPIO0_CTRL_OEOVER_Pos)
+	}
+	return nil
+}
+
+// SetInputSyncBypass bypasses the 2-flipflop input synchronizer of the pin
+// for this PIO block, cutting 2 cycles of input latency. It affects what 'in',
+// 'wait' and 'jmp pin' see on that pin, which helps meet timing when sampling
+// source synchronous data, e.g. a clock and data lines from an external chip.
+//
+// The synchronizer guards against metastability: without it an input changing
+// close to the sampling edge may read as neither level for a short while, and
+// unrelated signals read in the same cycle may disagree. Only bypass inputs
+// whose timing relative to the sampling clock is known to be safe.
+func (pio *PIO) SetInputSyncBypass(pin machine.Pin, bypass bool) {
+	bit := inputSyncBypassBit(uint8(pin))
+	mode := AliasClear
+	if bypass {
+		mode = AliasSet
+	}
+	AliasRegister(&pio.HW.INPUT_SYNC_BYPASS, mode).Set(bit)
+}
+
+// ConfigureOutPins prepares count consecutive pins starting at base to be
+// driven by 'out' instructions of this state machine. It routes each pin to
+// this state machine's PIO block, sets the pins as outputs and sets the out
+// pins in cfg, which must then be applied with Init or SetConfig.
+// ErrPinOutOfRange is returned, with nothing configured, if the pins run past GPIO 29.
+func (sm StateMachine) ConfigureOutPins(cfg *StateMachineConfig, base machine.Pin, count uint8) error {
+	if err := (PinRange{Base: base, Count: count}).Validate(); err != nil {
+		return err
+	}
+	mode := sm.PIO.PinMode()
+	for i := uint8(0); i < count; i++ {
+		(base + machine.Pin(i)).Configure(machine.PinConfig{Mode: mode})
+	}
+	sm.SetConsecutivePinDirs(base, count, true)
+	cfg.SetOutPins(base, count)
+	return nil
+}
+
+// InitSideSetPins prepares count consecutive pins starting at base to be
+// driven by side-set: each pin is routed to this state machine's PIO
+// block and set as an output. The side-set base and count must still be set
+// in the state machine configuration with SetSidePins and SetSideSet.
+//
+// Unlike 'out' and 'set' pins, side-set pins are easy to forget since
+// pioasm's generated init code does not set their direction.
+// ErrPinOutOfRange is returned, with nothing configured, if the pins run past GPIO 29.
+func (sm StateMachine) InitSideSetPins(base machine.Pin, count uint8) error {
+	if err := (PinRange{Base: base, Count: count}).Validate(); err != nil {
+		return err
+	}
+	mode := sm.PIO.PinMode()
+	for i := uint8(0); i < count; i++ {
+		(base + machine.Pin(i)).Configure(machine.PinConfig{Mode: mode})
+	}
+	sm.SetConsecutivePinDirs(base, count, true)
+	return nil
+}
+
+// SetConsecutivePinDirs sets a range of pins to either 'in' or 'out'
+//
+// It temporarily rewrites PINCTRL and injects 'set pindirs' instructions with Exec,
+// so the state machine must not be running its program: a running program would
+// see the modified pin mapping and contend with the injected instructions.
+// Use SetConsecutivePinDirsSafe on a state machine that may be enabled.
 func (sm StateMachine) SetConsecutivePinDirs(pin machine.Pin, count uint8, isOut bool) {
 	pinctl := &sm.HW().PINCTRL
 
@@ -250,6 +732,170 @@ func (sm StateMachine) SetConsecutivePinDirs(pin machine.Pin, count uint8, isOut
 	pinctl.Set(pinctrl_saved)
 }
 
+// SetConsecutivePinDirsSafe is like SetConsecutivePinDirs but halts the state
+// machine first if it is enabled and re-enables it afterwards. The program
+// resumes from where it was halted.
+func (sm StateMachine) SetConsecutivePinDirsSafe(pin machine.Pin, count uint8, isOut bool) {
+	enabled := sm.Enabled()
+	if enabled {
+		sm.SetEnabled(false)
+	}
+	sm.SetConsecutivePinDirs(pin, count, isOut)
+	if enabled {
+		sm.SetEnabled(true)
+	}
+}
+
+// SetPinRangeDirs is like SetConsecutivePinDirs but returns ErrPinOutOfRange
+// instead of wrapping around to GPIO 0 if r runs past GPIO 29.
+func (sm StateMachine) SetPinRangeDirs(r PinRange, isOut bool) error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	if r.Count > 0 {
+		sm.SetConsecutivePinDirs(r.Base, r.Count, isOut)
+	}
+	return nil
+}
+
+// ReadPins returns the levels of count (1..32) consecutive pins starting at
+// base as seen by the state machine, with base in bit 0. It forces 'in pins'
+// and 'push' instructions with IN_BASE temporarily set to base, which helps
+// confirm an input program sees the pins you think it does.
+//
+// The ISR is cleared and the RX FIFO must be empty, or the word read back is
+// not the sample and ReadPins panics. The state machine is halted while the
+// instructions run and re-enabled afterwards if it was running.
+func (sm StateMachine) ReadPins(base machine.Pin, count uint8) uint32 {
+	if count == 0 || count > 32 {
+		panic("pio: ReadPins count out of range 1..32")
+	}
+	if !sm.IsRxFIFOEmpty() {
+		panic("pio: ReadPins needs an empty RX FIFO")
+	}
+	enabled := sm.Enabled()
+	if enabled {
+		sm.SetEnabled(false)
+	}
+	hw := sm.HW()
+	pinctrl, shiftctrl := hw.PINCTRL.Get(), hw.SHIFTCTRL.Get()
+	hw.PINCTRL.Set(pinctrl&^rp.PIO0_SM0_PINCTRL_IN_BASE_Msk | uint32(base)<<rp.PIO0_SM0_PINCTRL_IN_BASE_Pos)
+	hw.SHIFTCTRL.Set(shiftctrl &^ rp.PIO0_SM0_SHIFTCTRL_AUTOPUSH) // Avoid a second push.
+
+	sm.Exec(EncodeMov(SrcDestISR, SrcDestNull))
+	sm.Exec(EncodeIn(SrcDestPins, uint16(count)))
+	sm.Exec(EncodePush(false, false))
+	value := sm.RxGet()
+
+	hw.SHIFTCTRL.Set(shiftctrl)
+	hw.PINCTRL.Set(pinctrl)
+	if enabled {
+		sm.SetEnabled(true)
+	}
+	if shiftctrl&rp.PIO0_SM0_SHIFTCTRL_IN_SHIFTDIR != 0 && count < 32 {
+		value >>= 32 - count // Shifting right leaves the sample in the top bits.
+	}
+	return value
+}
+
+// SetPinsWithMask sets the value of the pins selected by pinMask to the
+// corresponding bits of pinValues using forced 'set' instructions. The
+// state machine's PINCTRL is restored afterwards.
+func (sm StateMachine) SetPinsWithMask(pinValues, pinMask uint32) {
+	pinctl := &sm.HW().PINCTRL
+	pinctrlSaved := pinctl.Get()
+	for pinMask != 0 {
+		base := uint32(bits.TrailingZeros32(pinMask))
+		pinctl.Set((1 << rp.PIO0_SM0_PINCTRL_SET_COUNT_Pos) | (base << rp.PIO0_SM0_PINCTRL_SET_BASE_Pos))
+		sm.Exec(EncodeSet(SrcDestPins, uint16(pinValues>>base)&1))
+		pinMask &= pinMask - 1
+	}
+	pinctl.Set(pinctrlSaved)
+}
+
+// CurrentPinValues returns the physical levels of count consecutive pins
+// starting at base, with base in bit 0, from a single read of the SIO GPIO input
+// register. Unlike StateMachine.ReadPins it does not involve a state machine: it
+// observes the pads, so it shows whether the pins a state machine should be
+// driving are actually toggling, e.g. to diagnose a clock pin that does not move.
+func CurrentPinValues(base machine.Pin, count uint8) uint32 {
+	if count == 0 {
+		return 0
+	}
+	in := rp.SIO.GPIO_IN.Get() >> (uint32(base) & 0x1f)
+	if count >= 32 {
+		return in
+	}
+	return in & (1<<count - 1)
+}
+
+// ReleaseStickyOutput drives the pins selected by pinMask to pinValues, as
+// SetPinsWithMask, and then turns off OUT_STICKY in the live configuration, so a
+// pin is not left stuck at the last value the sticky configuration drove.
+// Call it before applying a configuration without OUT_STICKY.
+func (sm StateMachine) ReleaseStickyOutput(pinValues, pinMask uint32) {
+	sm.SetPinsWithMask(pinValues, pinMask)
+	AliasRegister(&sm.HW().EXECCTRL, AliasClear).Set(rp.PIO0_SM0_EXECCTRL_OUT_STICKY)
+}
+
+// SetScratchX sets the state machine's X scratch register to value by pushing
+// it through the TX FIFO and executing `pull` and `out x, 32`.
+//
+// The state machine should be stopped and its TX FIFO empty. The OSR is overwritten.
+func (sm StateMachine) SetScratchX(value uint32) {
+	sm.setRegister(SrcDestX, value)
+}
+
+// SetScratchY sets the state machine's Y scratch register to value. See SetScratchX.
+func (sm StateMachine) SetScratchY(value uint32) {
+	sm.setRegister(SrcDestY, value)
+}
+
+func (sm StateMachine) setRegister(dest SrcDest, value uint32) {
+	sm.TxPutBlocking(value)
+	sm.Exec(EncodePull(false, false))
+	sm.Exec(EncodeOut(dest, 32))
+}
+
+// SetPullCounter presets the output shift counter, the number of bits shifted
+// out of the OSR since the last pull, to bits (0..32). This reproduces exact
+// autopull timing for programs that rely on partially consumed OSR contents.
+//
+// It is destructive: the OSR is cleared by a forced 'mov osr, null' followed by
+// 'out null, bits'. The state machine is halted while the instructions run and
+// re-enabled afterwards if it was running.
+func (sm StateMachine) SetPullCounter(bits uint8) {
+	sm.presetShiftCounter(EncodeMov(SrcDestOSR, SrcDestNull), EncodeOut(SrcDestNull, uint16(bits)), bits)
+}
+
+// SetPushCounter presets the input shift counter, the number of bits shifted
+// into the ISR since the last push, to bits (0..32).
+//
+// It is destructive: the ISR is cleared by a forced 'mov isr, null' followed by
+// 'in null, bits'. If autopush is enabled and bits reaches the push threshold a
+// zero word is pushed to the RX FIFO. The state machine is halted while the
+// instructions run and re-enabled afterwards if it was running.
+func (sm StateMachine) SetPushCounter(bits uint8) {
+	sm.presetShiftCounter(EncodeMov(SrcDestISR, SrcDestNull), EncodeIn(SrcDestNull, uint16(bits)), bits)
+}
+
+func (sm StateMachine) presetShiftCounter(clear, shift uint16, bits uint8) {
+	if bits > 32 {
+		panic("pio: shift counter out of range 0..32")
+	}
+	enabled := sm.Enabled()
+	if enabled {
+		sm.SetEnabled(false)
+	}
+	sm.Exec(clear)
+	if bits > 0 {
+		sm.Exec(shift) // A bit count of 32 is encoded as 0.
+	}
+	if enabled {
+		sm.SetEnabled(true)
+	}
+}
+
 // TxPut puts a value into the state machine's TX FIFO.
 //
 // This function does not check for fullness. If the FIFO is full the FIFO
@@ -268,6 +914,51 @@ func (sm StateMachine) RxGet() uint32 {
 	return reg.Get()
 }
 
+// TxPutBlocking puts a value into the state machine's TX FIFO,
+// busy-waiting while the FIFO is full.
+func (sm StateMachine) TxPutBlocking(data uint32) {
+	for sm.IsTxFIFOFull() {
+	}
+	sm.TxPut(data)
+}
+
+// Transmit writes data into the state machine's TX FIFO without waiting for
+// space, as a fast non-blocking burst. Words written while the FIFO is full are dropped.
+// If checkOverflow is true the TXOVER flag is cleared before the burst and
+// ErrTxOverflow is returned if any word was dropped. See LastTxOverflowed.
+func (sm StateMachine) Transmit(data []uint32, checkOverflow bool) error {
+	if checkOverflow {
+		sm.LastTxOverflowed() // Clear stale flag.
+	}
+	reg := sm.tx()
+	for _, v := range data {
+		reg.Set(v)
+	}
+	if checkOverflow && sm.LastTxOverflowed() {
+		return ErrTxOverflow
+	}
+	return nil
+}
+
+// LastTxOverflowed reports whether a write to the TX FIFO was dropped because
+// the FIFO was full since the flag was last cleared, and clears the flag.
+// It checks the sticky FDEBUG TXOVER bit for this state machine.
+func (sm StateMachine) LastTxOverflowed() bool {
+	overflowed, clear := fdebugTxOverflowed(sm.PIO.HW.FDEBUG.Get(), sm.index)
+	if overflowed {
+		sm.PIO.HW.FDEBUG.Set(clear) // Write 1 to clear.
+	}
+	return overflowed
+}
+
+// RxGetBlocking reads a word of data from the state machine's RX FIFO,
+// busy-waiting while the FIFO is empty.
+func (sm StateMachine) RxGetBlocking() uint32 {
+	for sm.IsRxFIFOEmpty() {
+	}
+	return sm.RxGet()
+}
+
 // RxFIFOLevel returns the number of elements currently in a state machine's RX FIFO.
 // The number of elements returned is in the range 0..15.
 func (sm StateMachine) RxFIFOLevel() uint32 {
@@ -284,6 +975,40 @@ func (sm StateMachine) TxFIFOLevel() uint32 {
 	return (sm.PIO.HW.FLEVEL.Get() >> uint32(bitoffs)) & mask
 }
 
+// AllFIFOLevels returns the TX and RX FIFO levels of all four state machines,
+// indexed by state machine, from a single read of the FLEVEL register.
+func (pio *PIO) AllFIFOLevels() (tx [4]uint8, rx [4]uint8) {
+	return DecodeFIFOLevels(pio.HW.FLEVEL.Get())
+}
+
+// TxFIFOCapacity returns the depth of the state machine's TX FIFO as
+// configured by the FIFO join bits in SHIFTCTRL: 4 if not joined, 8 if
+// the RX FIFO is joined to the TX FIFO and 0 if the TX FIFO is joined to the RX FIFO.
+func (sm StateMachine) TxFIFOCapacity() uint8 {
+	shiftctl := sm.HW().SHIFTCTRL.Get()
+	switch {
+	case shiftctl&rp.PIO0_SM0_SHIFTCTRL_FJOIN_TX != 0:
+		return 8
+	case shiftctl&rp.PIO0_SM0_SHIFTCTRL_FJOIN_RX != 0:
+		return 0
+	}
+	return 4
+}
+
+// RxFIFOCapacity returns the depth of the state machine's RX FIFO as
+// configured by the FIFO join bits in SHIFTCTRL: 4 if not joined, 8 if
+// the TX FIFO is joined to the RX FIFO and 0 if the RX FIFO is joined to the TX FIFO.
+func (sm StateMachine) RxFIFOCapacity() uint8 {
+	shiftctl := sm.HW().SHIFTCTRL.Get()
+	switch {
+	case shiftctl&rp.PIO0_SM0_SHIFTCTRL_FJOIN_RX != 0:
+		return 8
+	case shiftctl&rp.PIO0_SM0_SHIFTCTRL_FJOIN_TX != 0:
+		return 0
+	}
+	return 4
+}
+
 // IsTxFIFOEmpty returns true if state machine's TX FIFO is empty.
 func (sm StateMachine) IsTxFIFOEmpty() bool {
 	return (sm.PIO.HW.FSTAT.Get() & (1 << (rp.PIO0_FSTAT_TXEMPTY_Pos + sm.index))) != 0
@@ -305,11 +1030,15 @@ func (sm StateMachine) IsRxFIFOFull() bool {
 }
 
 // ClearFIFOs clears the TX and RX FIFOs of a state machine.
+//
+// The FIFO join configuration is preserved: the FIFOs are flushed by toggling
+// FJOIN_RX twice through the atomic XOR alias, which leaves every SHIFTCTRL bit,
+// including a FIFO_JOIN_RX or FIFO_JOIN_TX set by Init, as it was found.
 func (sm StateMachine) ClearFIFOs() {
 	shiftctl := &sm.HW().SHIFTCTRL
-	// FIFOs are flushed when this bit is changed. Xoring twice returns bit to original state.
-	xorBits(shiftctl, rp.PIO0_SM0_SHIFTCTRL_FJOIN_RX)
-	xorBits(shiftctl, rp.PIO0_SM0_SHIFTCTRL_FJOIN_RX)
+	for _, toggle := range fifoFlushToggles {
+		xorBits(shiftctl, toggle)
+	}
 }
 
 func (cfg *StateMachineConfig) SetSidePins(pin machine.Pin) {
@@ -318,10 +1047,101 @@ func (cfg *StateMachineConfig) SetSidePins(pin machine.Pin) {
 }
 
 // Exec will immediately execute an instruction on the state machine
+//
+// Exec is a single register write and takes no lock. Forced instruction
+// sequences, such as setting a register through the FIFO followed by an 'out',
+// may be interleaved with instructions injected from an interrupt handler;
+// use ExecAtomic when instructions are injected from more than one context.
 func (sm StateMachine) Exec(instr uint16) {
 	sm.HW().INSTR.Set(uint32(instr))
 }
 
+// ExecBlocking executes an instruction on the state machine and busy-waits
+// until it has completed. See IsStalled.
+func (sm StateMachine) ExecBlocking(instr uint16) {
+	sm.Exec(instr)
+	for sm.IsStalled() {
+	}
+}
+
+// ExecAtomic executes instrs in order with interrupts disabled, waiting for
+// each to complete before writing the next, so instructions injected from an
+// interrupt handler cannot interleave with the sequence. An instruction that
+// stalls indefinitely, e.g. a 'wait' whose condition is never met, hangs
+// with interrupts disabled.
+func (sm StateMachine) ExecAtomic(instrs ...uint16) {
+	state := interrupt.Disable()
+	for _, instr := range instrs {
+		sm.ExecBlocking(instr)
+	}
+	interrupt.Restore(state)
+}
+
+// ExecSequence halts the state machine, executes instrs in order waiting for
+// each to complete, then re-enables it if it was running. It suits
+// initialization sequences, e.g. setting pin directions, preloading X and Y and
+// jumping to the program start, which must not interleave with the program.
+// As with ExecAtomic, an instruction that never completes hangs ExecSequence.
+func (sm StateMachine) ExecSequence(instrs []uint16) {
+	enabled := sm.Enabled()
+	if enabled {
+		sm.SetEnabled(false)
+	}
+	for _, instr := range instrs {
+		sm.ExecBlocking(instr)
+	}
+	if enabled {
+		sm.SetEnabled(true)
+	}
+}
+
+// IsStalled returns true if an instruction written with Exec (or executed via
+// 'out exec'/'mov exec') is stalled and has not yet completed, e.g. a 'wait'
+// whose condition is not met. This can be used to detect a wedged injected instruction.
+func (sm StateMachine) IsStalled() bool {
+	return sm.HW().EXECCTRL.Get()&rp.PIO0_SM0_EXECCTRL_EXEC_STALLED != 0
+}
+
+// WaitIdle blocks until all data queued in the TX FIFO has been consumed by
+// the program and the state machine is stalled waiting for more, i.e. on a
+// blocking 'pull' or an 'out' with autopull. Call it before disabling the state
+// machine or powering down the peripheral it drives.
+//
+// An empty TX FIFO alone does not mean the last word was transmitted: the
+// state machine may still be shifting it out of the OSR. WaitIdle clears the
+// sticky FDEBUG TXSTALL flag and waits for it to be set again, which only happens
+// once the program asks for data that is not there. It never returns for a program
+// that does not pull from the TX FIFO. See WaitIdleTimeout.
+func (sm StateMachine) WaitIdle() {
+	bit := sm.txStallBit()
+	sm.ClearTxStall()
+	for sm.PIO.HW.FDEBUG.Get()&bit == 0 {
+	}
+}
+
+// IsIdle reports whether the state machine is stalled on an empty TX FIFO,
+// waiting for data on a blocking 'pull' or an 'out' with autopull, e.g. to
+// decide when it is safe to sleep or reconfigure. It does not block and does
+// not modify any register.
+//
+// IsIdle reads the sticky FDEBUG TXSTALL flag, which stays set once the state
+// machine has stalled. Call ClearTxStall after queuing data so that IsIdle only
+// reports a stall seen since then.
+func (sm StateMachine) IsIdle() bool {
+	return sm.IsTxFIFOEmpty() && sm.PIO.HW.FDEBUG.Get()&sm.txStallBit() != 0
+}
+
+// ClearTxStall clears the sticky FDEBUG TXSTALL flag of the state machine.
+// A state machine that remains stalled sets it again on its next cycle.
+func (sm StateMachine) ClearTxStall() {
+	sm.PIO.HW.FDEBUG.Set(sm.txStallBit()) // Write 1 to clear.
+}
+
+// txStallBit returns the FDEBUG TXSTALL bit of this state machine.
+func (sm StateMachine) txStallBit() uint32 {
+	return uint32(1) << (rp.PIO0_FDEBUG_TXSTALL_Pos + uint32(sm.index))
+}
+
 type statemachineHW struct {
 	CLKDIV    volatile.Register32 // 0xC8 for SM0
 	EXECCTRL  volatile.Register32 // 0xCC
@@ -332,7 +1152,12 @@ type statemachineHW struct {
 }
 
 // HW returns a pointer to the configuration hardware registers for this state machine.
-func (sm StateMachine) HW() *statemachineHW { return sm.PIO.smHW(sm.index) }
+func (sm StateMachine) HW() *statemachineHW {
+	if sm.hw != nil {
+		return sm.hw
+	}
+	return sm.PIO.smHW(sm.index)
+}
 
 func (pio *PIO) smHW(index uint8) *statemachineHW {
 	if index > 3 {
@@ -351,7 +1176,18 @@ const (
 	REG_ALIAS_CLR_BITS = 0x3 << 12
 )
 
-// Gets the 'XOR' alias for a register
+// AliasMode selects one of the four address aliases of a peripheral register.
+type AliasMode uintptr
+
+// Register access alias modes.
+const (
+	AliasNormal AliasMode = REG_ALIAS_RW_BITS
+	AliasXOR    AliasMode = REG_ALIAS_XOR_BITS
+	AliasSet    AliasMode = REG_ALIAS_SET_BITS
+	AliasClear  AliasMode = REG_ALIAS_CLR_BITS
+)
+
+// AliasRegister gets the alias of a register for the given access mode.
 //
 // Registers have 'ALIAS' registers with special semantics, see
 // 2.1.2. Atomic Register Access in the RP2040 Datasheet
@@ -362,17 +1198,15 @@ const (
 //   - Addr + 0x1000 : atomic XOR on write
 //   - Addr + 0x2000 : atomic bitmask set on write
 //   - Addr + 0x3000 : atomic bitmask clear on write
+func AliasRegister(reg *volatile.Register32, mode AliasMode) *volatile.Register32 {
+	return (*volatile.Register32)(unsafe.Pointer(uintptr(unsafe.Pointer(reg)) | uintptr(mode)))
+}
+
+// Gets the 'XOR' alias for a register. See AliasRegister.
 func xorRegister(reg *volatile.Register32) *volatile.Register32 {
-	return (*volatile.Register32)(unsafe.Pointer(uintptr(unsafe.Pointer(reg)) | REG_ALIAS_XOR_BITS))
+	return AliasRegister(reg, AliasXOR)
 }
 
 func xorBits(reg *volatile.Register32, bits uint32) {
 	xorRegister(reg).Set(bits)
 }
-
-func boolToBit(b bool) uint32 {
-	if b {
-		return 1
-	}
-	return 0
-}
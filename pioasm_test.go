@@ -0,0 +1,167 @@
+package pio
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The testdata directory holds pioasm sources and the hex output of
+// 'pioasm -o hex' for them. Labels are replaced by the addresses pioasm
+// resolves them to, so each source line is the canonical form of its instruction.
+
+// pioasmFixture is a pioasm source file and its assembled instructions.
+type pioasmFixture struct {
+	source       []string // Instruction lines without comments.
+	sideSetCount uint8
+	sideSetOpt   bool
+	hex          []uint16
+}
+
+func readPioasmFixture(t *testing.T, name string) pioasmFixture {
+	t.Helper()
+	src, err := os.ReadFile(filepath.Join("testdata", name+".pio"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f pioasmFixture
+	for _, line := range strings.Split(string(src), "\n") {
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == ".side_set":
+			n, err := strconv.ParseUint(fields[1], 10, 8)
+			if err != nil {
+				t.Fatalf("%s.pio: %v", name, err)
+			}
+			f.sideSetCount = uint8(n)
+			f.sideSetOpt = len(fields) > 2 && fields[2] == "opt"
+		case strings.HasPrefix(fields[0], "."):
+		default:
+			f.source = append(f.source, strings.Join(fields, " "))
+		}
+	}
+	hex, err := os.ReadFile(filepath.Join("testdata", name+".hex"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range strings.Fields(string(hex)) {
+		v, err := strconv.ParseUint(word, 16, 16)
+		if err != nil {
+			t.Fatalf("%s.hex: %v", name, err)
+		}
+		f.hex = append(f.hex, uint16(v))
+	}
+	if len(f.hex) != len(f.source) {
+		t.Fatalf("%s: %d instructions in source, %d in hex", name, len(f.source), len(f.hex))
+	}
+	return f
+}
+
+func TestPioasmEncoders(t *testing.T) {
+	optSide := func(v uint16) uint16 { return EncodeSetSetOpt(1, v) }
+	tests := []struct {
+		name   string
+		instrs []uint16
+	}{
+		{"ws2812", []uint16{
+			EncodeOut(SrcDestX, 1) | EncodeSideSet(1, 0) | EncodeDelay(2),
+			EncodeInstrAndArgs(INSTR_BITS_JMP, 1, 3) | EncodeSideSet(1, 1) | EncodeDelay(1),
+			EncodeJmp(0) | EncodeSideSet(1, 1) | EncodeDelay(4),
+			EncodeNOP() | EncodeSideSet(1, 0) | EncodeDelay(4),
+		}},
+		{"uart_tx", []uint16{
+			EncodePull(false, true) | optSide(1) | EncodeDelay(7),
+			EncodeSet(SrcDestX, 7) | optSide(0) | EncodeDelay(7),
+			EncodeOut(SrcDestPins, 1),
+			EncodeInstrAndArgs(INSTR_BITS_JMP, 2, 2) | EncodeDelay(6),
+		}},
+		{"squarewave", []uint16{
+			EncodeSet(SrcDestPinDirs, 1),
+			EncodeSet(SrcDestPins, 1) | EncodeDelay(1),
+			EncodeSet(SrcDestPins, 0),
+			EncodeJmp(1),
+		}},
+		{"pwm", []uint16{
+			EncodePull(false, false) | optSide(0),
+			EncodeMov(SrcDestX, SrcDestOSR),
+			EncodeMov(SrcDestY, SrcDestISR),
+			EncodeInstrAndArgs(INSTR_BITS_JMP, 5, 5),
+			EncodeJmp(6) | optSide(1),
+			EncodeNOP(),
+			EncodeInstrAndArgs(INSTR_BITS_JMP, 4, 3),
+		}},
+		{"addition", []uint16{
+			EncodePull(false, true),
+			EncodeMovNot(SrcDestX, SrcDestOSR),
+			EncodePull(false, true),
+			EncodeMov(SrcDestY, SrcDestOSR),
+			EncodeJmp(6),
+			EncodeInstrAndArgs(INSTR_BITS_JMP, 2, 6),
+			EncodeInstrAndArgs(INSTR_BITS_JMP, 4, 5),
+			EncodeMovNot(SrcDestISR, SrcDestX),
+			EncodePush(false, true),
+		}},
+		{"clocked_input", []uint16{
+			EncodeWaitPin(false, 1),
+			EncodeWaitPin(true, 1),
+			EncodeIn(SrcDestPins, 1),
+		}},
+		{"st7789_parallel", []uint16{
+			EncodeOut(SrcDestPins, 8) | EncodeSideSet(1, 0),
+			EncodeNOP() | EncodeSideSet(1, 1),
+		}},
+	}
+	for _, tt := range tests {
+		f := readPioasmFixture(t, tt.name)
+		if len(tt.instrs) != len(f.hex) {
+			t.Errorf("%s: encoded %d instructions, pioasm %d", tt.name, len(tt.instrs), len(f.hex))
+			continue
+		}
+		for i, instr := range tt.instrs {
+			if instr != f.hex[i] {
+				t.Errorf("%s: %q encoded as %#04x, pioasm %#04x", tt.name, f.source[i], instr, f.hex[i])
+			}
+		}
+	}
+}
+
+func TestPioasmDisassemble(t *testing.T) {
+	for _, name := range []string{"ws2812", "uart_tx", "squarewave", "pwm", "addition", "clocked_input", "st7789_parallel"} {
+		f := readPioasmFixture(t, name)
+		for i, instr := range f.hex {
+			if got := DisassembleInstruction(instr, f.sideSetCount, f.sideSetOpt); got != f.source[i] {
+				t.Errorf("%s: DisassembleInstruction(%#04x) = %q, want %q", name, instr, got, f.source[i])
+			}
+			// Side-set and delay as written in the source.
+			d := DecodeInstruction(instr, f.sideSetCount, f.sideSetOpt)
+			var wantSide, wantDelay string
+			fields := strings.Fields(f.source[i])
+			for j, field := range fields {
+				switch {
+				case field == "side":
+					wantSide = fields[j+1]
+				case strings.HasPrefix(field, "["):
+					wantDelay = strings.Trim(field, "[]")
+				}
+			}
+			if d.HasSideSet != (wantSide != "") || d.HasSideSet && strconv.Itoa(int(d.SideSet)) != wantSide {
+				t.Errorf("%s: %q decoded side-set %v %d", name, f.source[i], d.HasSideSet, d.SideSet)
+			}
+			if wantDelay == "" {
+				wantDelay = "0"
+			}
+			if strconv.Itoa(int(d.Delay)) != wantDelay {
+				t.Errorf("%s: %q decoded delay %d", name, f.source[i], d.Delay)
+			}
+			if d.Major != instr&INSTR_BITS_Msk && d.Major != INSTR_BITS_PULL {
+				t.Errorf("%s: %q decoded major %#04x", name, f.source[i], d.Major)
+			}
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package pio
+
+// Program is a PIO program as output by pioasm.
+type Program struct {
+	// Instructions holds the program binary code in 16-bit words.
+	Instructions []uint16
+	// Origin indicates where in the PIO execution memory the program must be loaded,
+	// or -1 if the code is position independent.
+	Origin int8
+
+	// WrapTarget and Wrap are the program relative addresses of the
+	// .wrap_target and .wrap directives.
+	WrapTarget uint8
+	Wrap       uint8
+	// SideSetCount is the number of side-set bits declared with .side_set,
+	// not including the enable bit of an optional side-set.
+	SideSetCount uint8
+	// SideSetOpt is true if side-set is optional (.side_set n opt).
+	SideSetOpt bool
+	// SideSetPindirs is true if side-set drives pin directions (.side_set n pindirs).
+	SideSetPindirs bool
+}
+
+// DefaultConfig returns the default state machine configuration for the
+// program loaded at offset, with the wrap and side-set settings applied
+// from the program's metadata. This replaces the per-program default
+// config function emitted by pioasm.
+func (p *Program) DefaultConfig(offset uint8) StateMachineConfig {
+	cfg := DefaultStateMachineConfig()
+	cfg.SetWrap(offset+p.WrapTarget, offset+p.Wrap)
+	if p.SideSetCount > 0 {
+		count := p.SideSetCount
+		if p.SideSetOpt {
+			count++ // Enable bit.
+		}
+		cfg.SetSideSet(count, p.SideSetOpt, p.SideSetPindirs)
+	}
+	return cfg
+}
+
+// Hash returns a 32-bit FNV-1a hash of the program's instructions and origin.
+// Since PIO instruction memory is write-only, drivers that swap programs can
+// remember the hash of what they loaded at an offset and skip reloading an
+// identical program, which also avoids glitching pins. Wrap and side-set
+// metadata live in the state machine configuration and are not hashed.
+func (p *Program) Hash() uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	h = (h ^ uint32(uint8(p.Origin))) * prime32
+	for _, instr := range p.Instructions {
+		h = (h ^ uint32(instr&0xff)) * prime32
+		h = (h ^ uint32(instr>>8)) * prime32
+	}
+	return h
+}
+
+// InsertDelay adds cycles delay cycles to the instruction at index afterIndex,
+// so execution pauses that much longer after it. It is useful to tune the timing
+// of an assembled program against real hardware without re-running pioasm.
+//
+// The delay shares the 5-bit delay/side-set field with side-set, so the
+// program's SideSetCount and SideSetOpt limit the total delay; ErrDelayTooLarge
+// is returned and the program left unchanged if it does not fit. ErrInvalidIndex
+// is returned if afterIndex is not an instruction of the program.
+func (p *Program) InsertDelay(afterIndex int, cycles uint8) error {
+	if afterIndex < 0 || afterIndex >= len(p.Instructions) {
+		return ErrInvalidIndex
+	}
+	sideSetBits := p.SideSetCount
+	if p.SideSetOpt {
+		sideSetBits++
+	}
+	instr := p.Instructions[afterIndex]
+	delay := uint16(DecodeInstruction(instr, p.SideSetCount, p.SideSetOpt).Delay) + uint16(cycles)
+	encoded, err := EncodeDelayChecked(delay, sideSetBits)
+	if err != nil {
+		return err
+	}
+	delayMask := EncodeDelay(1<<(5-sideSetBits) - 1)
+	p.Instructions[afterIndex] = instr&^delayMask | encoded
+	return nil
+}
+
+// CycleCount statically estimates the period of the program's main loop in
+// state machine cycles: the cycles spent from the wrap target until execution
+// returns to it, through the wrap or a jump. Each instruction costs one cycle
+// plus its delay. Conditional jumps make the period vary between min and max.
+// Divide by the state machine frequency to get the loop period in seconds.
+//
+// Stalls, e.g. on 'wait' or a blocking 'pull', are not counted. max is -1 if the
+// period is unbounded: the loop contains an inner loop, such as a 'jmp x--'
+// countdown, or leaves the program. min is -1 too if execution never returns
+// to the wrap target.
+func (p *Program) CycleCount() (min, max int) {
+	n := len(p.Instructions)
+	if n == 0 || int(p.WrapTarget) >= n {
+		return -1, -1
+	}
+	start := int(p.WrapTarget)
+	// Each pc has at most two successors: the next instruction, after the wrap,
+	// and a jump target. Reaching start ends the loop.
+	var succ [32][2]int
+	var nsucc [32]int
+	var cost [32]int
+	for pc, instr := range p.Instructions {
+		cost[pc] = 1 + int(DecodeInstruction(instr, p.SideSetCount, p.SideSetOpt).Delay)
+		next := pc + 1
+		if pc == int(p.Wrap) {
+			next = start
+		}
+		if instr&INSTR_BITS_Msk == INSTR_BITS_JMP {
+			succ[pc][nsucc[pc]] = int(instr & 0x1f)
+			nsucc[pc]++
+			if instr&0xe0 == 0 { // Unconditional jumps never fall through.
+				continue
+			}
+		}
+		succ[pc][nsucc[pc]] = next
+		nsucc[pc]++
+	}
+
+	// The period is unbounded if a loop that does not pass through start, or
+	// a jump out of the program, is reachable from start.
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	var state [32]uint8
+	unbounded := false
+	var visit func(pc int)
+	visit = func(pc int) {
+		state[pc] = onPath
+		for _, target := range succ[pc][:nsucc[pc]] {
+			switch {
+			case target == start:
+			case target >= n || state[target] == onPath:
+				unbounded = true
+			case state[target] == unvisited:
+				visit(target)
+			}
+		}
+		state[pc] = done
+	}
+	visit(start)
+
+	// Shortest and longest cycles from each pc back to start, -1 if start is
+	// not reachable. With no loops the longest paths settle within n rounds,
+	// and shortest paths never repeat an instruction so they settle too.
+	var shortest, longest [32]int
+	for pc := range shortest[:n] {
+		shortest[pc], longest[pc] = -1, -1
+	}
+	for round := 0; round <= n; round++ {
+		for pc := 0; pc < n; pc++ {
+			if state[pc] != done {
+				continue
+			}
+			lo, hi := -1, -1
+			for _, target := range succ[pc][:nsucc[pc]] {
+				tlo, thi := 0, 0
+				if target != start {
+					if target >= n || shortest[target] < 0 {
+						continue
+					}
+					tlo, thi = shortest[target], longest[target]
+				}
+				if lo < 0 || tlo < lo {
+					lo = tlo
+				}
+				if thi > hi {
+					hi = thi
+				}
+			}
+			if lo >= 0 {
+				shortest[pc], longest[pc] = cost[pc]+lo, cost[pc]+hi
+			}
+		}
+	}
+	min, max = shortest[start], longest[start]
+	if unbounded || min < 0 {
+		max = -1
+	}
+	return min, max
+}
+
+// ProgramFromHex returns a Program from raw pioasm hex output, e.g.
+//
+//	pio.ProgramFromHex(-1, 0x6008, 0xb042)
+//
+// The instructions are copied so the returned program does not alias hex.
+// The wrap is set to span the whole program.
+func ProgramFromHex(origin int8, hex ...uint16) *Program {
+	instructions := make([]uint16, len(hex))
+	copy(instructions, hex)
+	var wrap uint8
+	if len(hex) > 0 {
+		wrap = uint8(len(hex) - 1)
+	}
+	return &Program{
+		Instructions: instructions,
+		Origin:       origin,
+		Wrap:         wrap,
+	}
+}
+
+// RemapPins returns a copy of the program with its absolute GPIO references
+// shifted by shift pins, so one assembled program can target a different pin
+// group without re-running pioasm. ErrPinOutOfRange is returned if a
+// remapped pin falls outside 0..29.
+//
+// Only 'wait gpio' encodes an absolute GPIO number and is patched. All other
+// pin references are relative to a base set in the state machine configuration
+// and are moved by changing that base instead: 'out', 'set' and 'mov pins'
+// (SetOutPins, SetSetPins), side-set (SetSidePins), 'in' and 'wait pin'
+// (SetInPins) and 'jmp pin' (SetJmpPin). SET immediates are data, not pin numbers.
+func (p *Program) RemapPins(shift int8) (*Program, error) {
+	remapped := *p
+	remapped.Instructions = make([]uint16, len(p.Instructions))
+	copy(remapped.Instructions, p.Instructions)
+	for i, instr := range remapped.Instructions {
+		// WAIT source 00 is GPIO; index in bits 4..0.
+		if instr&INSTR_BITS_Msk != INSTR_BITS_WAIT || instr&0x60 != 0 {
+			continue
+		}
+		pin := int(instr&0x1f) + int(shift)
+		if pin < 0 || pin > 29 {
+			return nil, ErrPinOutOfRange
+		}
+		remapped.Instructions[i] = instr&^0x1f | uint16(pin)
+	}
+	return &remapped, nil
+}
+
+// LinkPrograms concatenates position independent program fragments into a single
+// program, so larger programs can be built from reusable pieces. Fragments
+// with a fixed origin are rejected with ErrProgramNotRelocatable.
+//
+// JMP targets in each fragment are relative to the fragment's start, as emitted
+// by pioasm, and are relocated to the fragment's position in the linked program.
+// A fragment may jump past its own end into the fragments that follow it, e.g. a
+// jump to its length lands on the first instruction of the next fragment.
+// The linked program wraps from the first fragment's wrap target to the last
+// fragment's wrap. All fragments must share the same side-set settings.
+func LinkPrograms(programs []*Program) (*Program, error) {
+	total := 0
+	for _, p := range programs {
+		if p.Origin >= 0 {
+			return nil, ErrProgramNotRelocatable
+		}
+		total += len(p.Instructions)
+	}
+	if total > 32 {
+		return nil, ErrProgramTooLarge
+	}
+	linked := &Program{
+		Instructions: make([]uint16, 0, total),
+		Origin:       -1,
+	}
+	for i, p := range programs {
+		base := len(linked.Instructions)
+		if i == 0 {
+			linked.WrapTarget = p.WrapTarget
+			linked.SideSetCount = p.SideSetCount
+			linked.SideSetOpt = p.SideSetOpt
+			linked.SideSetPindirs = p.SideSetPindirs
+		} else if p.SideSetCount != linked.SideSetCount || p.SideSetOpt != linked.SideSetOpt ||
+			p.SideSetPindirs != linked.SideSetPindirs {
+			return nil, ErrSideSetMismatch
+		}
+		for _, instr := range p.Instructions {
+			if instr&INSTR_BITS_Msk == INSTR_BITS_JMP {
+				target := base + int(instr&0x1f)
+				if target >= total {
+					return nil, ErrJmpOutOfRange
+				}
+				instr = instr&^0x1f | uint16(target)
+			}
+			linked.Instructions = append(linked.Instructions, instr)
+		}
+		linked.Wrap = uint8(base) + p.Wrap
+	}
+	return linked, nil
+}
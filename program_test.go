@@ -0,0 +1,186 @@
+package pio
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInsertDelay(t *testing.T) {
+	nop := EncodeMov(SrcDestY, SrcDestY)
+	tests := []struct {
+		sideSetCount uint8
+		sideSetOpt   bool
+		delay        uint8 // Existing delay of the instruction.
+		cycles       uint8
+		wantDelay    uint8
+		wantErr      error
+	}{
+		{0, false, 0, 31, 31, nil},
+		{0, false, 30, 1, 31, nil},
+		{0, false, 31, 1, 0, ErrDelayTooLarge},
+		{0, false, 0, 32, 0, ErrDelayTooLarge},
+		{2, false, 3, 4, 7, nil},
+		{2, false, 4, 4, 0, ErrDelayTooLarge},
+		{1, true, 7, 0, 7, nil},
+		{1, true, 7, 1, 0, ErrDelayTooLarge}, // Enable bit leaves 3 delay bits.
+		{4, true, 0, 1, 0, ErrDelayTooLarge}, // No delay bits left.
+	}
+	for _, tt := range tests {
+		// Side-set the highest value so that the delay must not clobber it.
+		sideSet := uint16(1)<<tt.sideSetCount - 1
+		instr := nop | EncodeDelay(uint16(tt.delay))
+		if tt.sideSetOpt {
+			instr |= EncodeSetSetOpt(uint16(tt.sideSetCount), sideSet)
+		} else {
+			instr |= EncodeSideSet(uint16(tt.sideSetCount), sideSet)
+		}
+		p := &Program{
+			Instructions: []uint16{nop, instr},
+			Origin:       -1,
+			SideSetCount: tt.sideSetCount,
+			SideSetOpt:   tt.sideSetOpt,
+		}
+		err := p.InsertDelay(1, tt.cycles)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("count=%d opt=%v delay=%d: InsertDelay(%d) error = %v, want %v", tt.sideSetCount, tt.sideSetOpt, tt.delay, tt.cycles, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if p.Instructions[1] != instr {
+				t.Errorf("count=%d opt=%v: failed InsertDelay modified instruction to %#04x", tt.sideSetCount, tt.sideSetOpt, p.Instructions[1])
+			}
+			continue
+		}
+		got := DecodeInstruction(p.Instructions[1], tt.sideSetCount, tt.sideSetOpt)
+		if got.Delay != tt.wantDelay {
+			t.Errorf("count=%d opt=%v delay=%d: InsertDelay(%d) delay = %d, want %d", tt.sideSetCount, tt.sideSetOpt, tt.delay, tt.cycles, got.Delay, tt.wantDelay)
+		}
+		want := DecodeInstruction(instr, tt.sideSetCount, tt.sideSetOpt)
+		if got.SideSet != want.SideSet || got.HasSideSet != want.HasSideSet {
+			t.Errorf("count=%d opt=%v: InsertDelay changed side-set", tt.sideSetCount, tt.sideSetOpt)
+		}
+		if p.Instructions[0] != nop {
+			t.Errorf("InsertDelay modified another instruction")
+		}
+	}
+}
+
+func TestInsertDelayIndex(t *testing.T) {
+	p := &Program{Instructions: []uint16{EncodeMov(SrcDestY, SrcDestY)}, Origin: -1}
+	for _, index := range []int{-1, 1} {
+		if err := p.InsertDelay(index, 1); !errors.Is(err, ErrInvalidIndex) {
+			t.Errorf("InsertDelay(%d) error = %v, want ErrInvalidIndex", index, err)
+		}
+	}
+}
+
+func TestProgramFromHexCopies(t *testing.T) {
+	hex := []uint16{0x6008, 0xb042}
+	p := ProgramFromHex(-1, hex...)
+	hex[0] = 0
+	if p.Instructions[0] != 0x6008 {
+		t.Errorf("modifying the input changed the program to %#04x", p.Instructions[0])
+	}
+	p.Instructions[1] = 0
+	if hex[1] != 0xb042 {
+		t.Errorf("modifying the program changed the input to %#04x", hex[1])
+	}
+	if p.Origin != -1 || p.WrapTarget != 0 || p.Wrap != 1 {
+		t.Errorf("got origin %d, wrap %d..%d; want -1, 0..1", p.Origin, p.WrapTarget, p.Wrap)
+	}
+}
+
+func TestRemapPins(t *testing.T) {
+	p := ProgramFromHex(-1,
+		EncodeWaitGPIO(true, 4),
+		EncodeWaitPin(true, 4), // Relative to the 'in' base, not remapped.
+		EncodeSet(SrcDestPins, 4),
+		EncodeWaitGPIO(false, 10)|EncodeDelay(3),
+	)
+	orig := append([]uint16(nil), p.Instructions...)
+	got, err := p.RemapPins(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint16{
+		EncodeWaitGPIO(true, 9),
+		EncodeWaitPin(true, 4),
+		EncodeSet(SrcDestPins, 4),
+		EncodeWaitGPIO(false, 15) | EncodeDelay(3),
+	}
+	for i := range want {
+		if got.Instructions[i] != want[i] {
+			t.Errorf("instruction %d = %#04x, want %#04x", i, got.Instructions[i], want[i])
+		}
+		if p.Instructions[i] != orig[i] {
+			t.Errorf("RemapPins modified the original instruction %d", i)
+		}
+	}
+	back, err := got.RemapPins(-5)
+	if err != nil || back.Instructions[0] != orig[0] || back.Instructions[3] != orig[3] {
+		t.Errorf("RemapPins(-5) did not undo RemapPins(5): %v", err)
+	}
+	for _, shift := range []int8{-5, 20} {
+		if _, err := p.RemapPins(shift); !errors.Is(err, ErrPinOutOfRange) {
+			t.Errorf("RemapPins(%d) error = %v, want ErrPinOutOfRange", shift, err)
+		}
+	}
+}
+
+func TestCycleCount(t *testing.T) {
+	nop := EncodeMov(SrcDestY, SrcDestY)
+	jmpNotX := func(addr uint16) uint16 { return EncodeInstrAndArgs(INSTR_BITS_JMP, 1, addr) }
+	jmpXDec := func(addr uint16) uint16 { return EncodeInstrAndArgs(INSTR_BITS_JMP, 2, addr) }
+	tests := []struct {
+		name     string
+		p        *Program
+		min, max int
+	}{
+		{"empty", &Program{}, -1, -1},
+		{"wrap target outside", &Program{Instructions: []uint16{nop}, WrapTarget: 1}, -1, -1},
+		{"straight", &Program{Instructions: []uint16{nop | EncodeDelay(2), nop | EncodeDelay(1), nop}, Wrap: 2}, 6, 6},
+		{"outside wrap", &Program{Instructions: []uint16{nop | EncodeDelay(9), nop, nop | EncodeDelay(3), nop}, WrapTarget: 1, Wrap: 2}, 5, 5},
+		{"jump back", &Program{Instructions: []uint16{nop, EncodeJmp(0), nop}, Wrap: 2}, 2, 2},
+		{"branch", &Program{Instructions: []uint16{jmpNotX(2), nop | EncodeDelay(3), nop}, Wrap: 2}, 2, 6},
+		{"inner loop", &Program{Instructions: []uint16{nop, jmpXDec(1), nop | EncodeDelay(4)}, Wrap: 2}, 7, -1},
+		{"never returns", &Program{Instructions: []uint16{EncodeJmp(1), EncodeJmp(1)}, Wrap: 1}, -1, -1},
+		{
+			// Side-set bits are not delay cycles.
+			"side-set",
+			&Program{Instructions: []uint16{nop | EncodeSideSet(2, 3) | EncodeDelay(1)}, SideSetCount: 2},
+			2, 2,
+		},
+	}
+	for _, tt := range tests {
+		min, max := tt.p.CycleCount()
+		if min != tt.min || max != tt.max {
+			t.Errorf("%s: CycleCount = %d, %d; want %d, %d", tt.name, min, max, tt.min, tt.max)
+		}
+	}
+}
+
+func TestCycleCountChainedBranches(t *testing.T) {
+	// 16 diamonds: each 'jmp !x' either skips a 'nop [1]' or falls through to
+	// it, and both paths meet at the next diamond. The loop has 2^16 paths.
+	nop := EncodeMov(SrcDestY, SrcDestY)
+	p := &Program{Instructions: make([]uint16, 32), Wrap: 31}
+	for i := 0; i < 32; i += 2 {
+		p.Instructions[i] = EncodeInstrAndArgs(INSTR_BITS_JMP, 1, uint16(i+2)&0x1f)
+		p.Instructions[i+1] = nop | EncodeDelay(1)
+	}
+	done := make(chan struct{})
+	var min, max int
+	go func() {
+		min, max = p.CycleCount()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("CycleCount did not finish within a second")
+	}
+	if min != 16 || max != 48 {
+		t.Errorf("CycleCount = %d, %d; want 16, 48", min, max)
+	}
+}
@@ -0,0 +1,108 @@
+package pio
+
+// State machine register fields used by the configuration helpers. They mirror
+// the PIO0_SM0_* constants of device/rp so that StateMachineConfig and the
+// other hardware independent code build, and can be tested, off-target.
+const (
+	pio0_SM0_CLKDIV_FRAC_Pos            = 0x8
+	pio0_SM0_CLKDIV_INT_Pos             = 0x10
+	pio0_SM0_EXECCTRL_INLINE_OUT_EN_Msk = 0x40000
+	pio0_SM0_EXECCTRL_INLINE_OUT_EN_Pos = 0x12
+	pio0_SM0_EXECCTRL_JMP_PIN_Msk       = 0x1f000000
+	pio0_SM0_EXECCTRL_JMP_PIN_Pos       = 0x18
+	pio0_SM0_EXECCTRL_OUT_EN_SEL_Msk    = 0xf80000
+	pio0_SM0_EXECCTRL_OUT_EN_SEL_Pos    = 0x13
+	pio0_SM0_EXECCTRL_OUT_STICKY_Msk    = 0x20000
+	pio0_SM0_EXECCTRL_OUT_STICKY_Pos    = 0x11
+	pio0_SM0_EXECCTRL_SIDE_EN           = 0x40000000
+	pio0_SM0_EXECCTRL_SIDE_EN_Msk       = 0x40000000
+	pio0_SM0_EXECCTRL_SIDE_EN_Pos       = 0x1e
+	pio0_SM0_EXECCTRL_SIDE_PINDIR       = 0x20000000
+	pio0_SM0_EXECCTRL_SIDE_PINDIR_Msk   = 0x20000000
+	pio0_SM0_EXECCTRL_SIDE_PINDIR_Pos   = 0x1d
+	pio0_SM0_EXECCTRL_STATUS_N_Msk      = 0xf
+	pio0_SM0_EXECCTRL_STATUS_N_Pos      = 0x0
+	pio0_SM0_EXECCTRL_STATUS_SEL_Msk    = 0x10
+	pio0_SM0_EXECCTRL_STATUS_SEL_Pos    = 0x4
+	pio0_SM0_EXECCTRL_WRAP_BOTTOM_Msk   = 0xf80
+	pio0_SM0_EXECCTRL_WRAP_BOTTOM_Pos   = 0x7
+	pio0_SM0_EXECCTRL_WRAP_TOP_Msk      = 0x1f000
+	pio0_SM0_EXECCTRL_WRAP_TOP_Pos      = 0xc
+	pio0_SM0_PINCTRL_IN_BASE_Msk        = 0xf8000
+	pio0_SM0_PINCTRL_IN_BASE_Pos        = 0xf
+	pio0_SM0_PINCTRL_OUT_BASE_Msk       = 0x1f
+	pio0_SM0_PINCTRL_OUT_BASE_Pos       = 0x0
+	pio0_SM0_PINCTRL_OUT_COUNT_Msk      = 0x3f00000
+	pio0_SM0_PINCTRL_OUT_COUNT_Pos      = 0x14
+	pio0_SM0_PINCTRL_SET_BASE_Msk       = 0x3e0
+	pio0_SM0_PINCTRL_SET_BASE_Pos       = 0x5
+	pio0_SM0_PINCTRL_SET_COUNT_Msk      = 0x1c000000
+	pio0_SM0_PINCTRL_SET_COUNT_Pos      = 0x1a
+	pio0_SM0_PINCTRL_SIDESET_BASE_Msk   = 0x7c00
+	pio0_SM0_PINCTRL_SIDESET_BASE_Pos   = 0xa
+	pio0_SM0_PINCTRL_SIDESET_COUNT_Msk  = 0xe0000000
+	pio0_SM0_PINCTRL_SIDESET_COUNT_Pos  = 0x1d
+	pio0_SM0_SHIFTCTRL_AUTOPULL         = 0x20000
+	pio0_SM0_SHIFTCTRL_AUTOPULL_Msk     = 0x20000
+	pio0_SM0_SHIFTCTRL_AUTOPULL_Pos     = 0x11
+	pio0_SM0_SHIFTCTRL_AUTOPUSH         = 0x10000
+	pio0_SM0_SHIFTCTRL_AUTOPUSH_Msk     = 0x10000
+	pio0_SM0_SHIFTCTRL_AUTOPUSH_Pos     = 0x10
+	pio0_SM0_SHIFTCTRL_FJOIN_RX_Msk     = 0x80000000
+	pio0_SM0_SHIFTCTRL_FJOIN_TX_Msk     = 0x40000000
+	pio0_SM0_SHIFTCTRL_FJOIN_TX_Pos     = 0x1e
+	pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk  = 0x40000
+	pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos  = 0x12
+	pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk = 0x80000
+	pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos = 0x13
+	pio0_SM0_SHIFTCTRL_PULL_THRESH_Msk  = 0x3e000000
+	pio0_SM0_SHIFTCTRL_PULL_THRESH_Pos  = 0x19
+	pio0_SM0_SHIFTCTRL_PUSH_THRESH_Msk  = 0x1f00000
+	pio0_SM0_SHIFTCTRL_PUSH_THRESH_Pos  = 0x14
+)
+
+// Block register fields, mirroring the PIO0_CTRL_* and PIO0_FDEBUG_* constants of device/rp.
+const (
+	pio0_CTRL_SM_ENABLE_Msk = 0xf
+	pio0_CTRL_SM_ENABLE_Pos = 0x0
+	pio0_FDEBUG_TXOVER_Pos  = 0x10
+)
+
+// The helpers below compute the register values read and written by the
+// hardware accessors, so their bit layout can be tested off-target.
+
+// ctrlEnabledMask extracts the state machine enable bits of a CTRL value.
+func ctrlEnabledMask(ctrl uint32) uint32 {
+	return (ctrl & pio0_CTRL_SM_ENABLE_Msk) >> pio0_CTRL_SM_ENABLE_Pos
+}
+
+// ctrlWithEnabledMask returns ctrl with its state machine enable bits replaced
+// by the low nibble of mask.
+func ctrlWithEnabledMask(ctrl, mask uint32) uint32 {
+	return ctrl&^pio0_CTRL_SM_ENABLE_Msk | (mask<<pio0_CTRL_SM_ENABLE_Pos)&pio0_CTRL_SM_ENABLE_Msk
+}
+
+// ctrlEnabled reports whether the state machine at index is enabled in a CTRL value.
+func ctrlEnabled(ctrl uint32, index uint8) bool {
+	return ctrl&(1<<(pio0_CTRL_SM_ENABLE_Pos+uint32(index))) != 0
+}
+
+// fdebugTxOverflowed reports whether the TXOVER flag of the state machine at
+// index is set in an FDEBUG value, and returns the bit to write to clear it.
+func fdebugTxOverflowed(fdebug uint32, index uint8) (overflowed bool, clear uint32) {
+	bit := uint32(1) << (pio0_FDEBUG_TXOVER_Pos + uint32(index))
+	return fdebug&bit != 0, bit
+}
+
+// inputSyncBypassBit returns the INPUT_SYNC_BYPASS bit of a GPIO.
+func inputSyncBypassBit(pin uint8) uint32 {
+	if pin > 31 {
+		panic("pio: SetInputSyncBypass pin out of range")
+	}
+	return 1 << pin
+}
+
+// fifoFlushToggles are written, in order, to the XOR alias of SHIFTCTRL to
+// flush both FIFOs. Changing FJOIN_RX flushes them and the second write changes
+// it back, so the join configured with SetFIFOJoin is left as it was.
+var fifoFlushToggles = [2]uint32{pio0_SM0_SHIFTCTRL_FJOIN_RX_Msk, pio0_SM0_SHIFTCTRL_FJOIN_RX_Msk}
@@ -0,0 +1,81 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"device/rp"
+	"testing"
+)
+
+// TestRegsMatchDevice checks the register fields mirrored in regs.go against device/rp.
+func TestRegsMatchDevice(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want uint32
+	}{
+		{"pio0_CTRL_SM_ENABLE_Msk", pio0_CTRL_SM_ENABLE_Msk, rp.PIO0_CTRL_SM_ENABLE_Msk},
+		{"pio0_CTRL_SM_ENABLE_Pos", pio0_CTRL_SM_ENABLE_Pos, rp.PIO0_CTRL_SM_ENABLE_Pos},
+		{"pio0_FDEBUG_TXOVER_Pos", pio0_FDEBUG_TXOVER_Pos, rp.PIO0_FDEBUG_TXOVER_Pos},
+		{"pio0_SM0_CLKDIV_FRAC_Pos", pio0_SM0_CLKDIV_FRAC_Pos, rp.PIO0_SM0_CLKDIV_FRAC_Pos},
+		{"pio0_SM0_CLKDIV_INT_Pos", pio0_SM0_CLKDIV_INT_Pos, rp.PIO0_SM0_CLKDIV_INT_Pos},
+		{"pio0_SM0_EXECCTRL_INLINE_OUT_EN_Msk", pio0_SM0_EXECCTRL_INLINE_OUT_EN_Msk, rp.PIO0_SM0_EXECCTRL_INLINE_OUT_EN_Msk},
+		{"pio0_SM0_EXECCTRL_INLINE_OUT_EN_Pos", pio0_SM0_EXECCTRL_INLINE_OUT_EN_Pos, rp.PIO0_SM0_EXECCTRL_INLINE_OUT_EN_Pos},
+		{"pio0_SM0_EXECCTRL_JMP_PIN_Msk", pio0_SM0_EXECCTRL_JMP_PIN_Msk, rp.PIO0_SM0_EXECCTRL_JMP_PIN_Msk},
+		{"pio0_SM0_EXECCTRL_JMP_PIN_Pos", pio0_SM0_EXECCTRL_JMP_PIN_Pos, rp.PIO0_SM0_EXECCTRL_JMP_PIN_Pos},
+		{"pio0_SM0_EXECCTRL_OUT_EN_SEL_Msk", pio0_SM0_EXECCTRL_OUT_EN_SEL_Msk, rp.PIO0_SM0_EXECCTRL_OUT_EN_SEL_Msk},
+		{"pio0_SM0_EXECCTRL_OUT_EN_SEL_Pos", pio0_SM0_EXECCTRL_OUT_EN_SEL_Pos, rp.PIO0_SM0_EXECCTRL_OUT_EN_SEL_Pos},
+		{"pio0_SM0_EXECCTRL_OUT_STICKY_Msk", pio0_SM0_EXECCTRL_OUT_STICKY_Msk, rp.PIO0_SM0_EXECCTRL_OUT_STICKY_Msk},
+		{"pio0_SM0_EXECCTRL_OUT_STICKY_Pos", pio0_SM0_EXECCTRL_OUT_STICKY_Pos, rp.PIO0_SM0_EXECCTRL_OUT_STICKY_Pos},
+		{"pio0_SM0_EXECCTRL_SIDE_EN", pio0_SM0_EXECCTRL_SIDE_EN, rp.PIO0_SM0_EXECCTRL_SIDE_EN},
+		{"pio0_SM0_EXECCTRL_SIDE_EN_Msk", pio0_SM0_EXECCTRL_SIDE_EN_Msk, rp.PIO0_SM0_EXECCTRL_SIDE_EN_Msk},
+		{"pio0_SM0_EXECCTRL_SIDE_EN_Pos", pio0_SM0_EXECCTRL_SIDE_EN_Pos, rp.PIO0_SM0_EXECCTRL_SIDE_EN_Pos},
+		{"pio0_SM0_EXECCTRL_SIDE_PINDIR", pio0_SM0_EXECCTRL_SIDE_PINDIR, rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR},
+		{"pio0_SM0_EXECCTRL_SIDE_PINDIR_Msk", pio0_SM0_EXECCTRL_SIDE_PINDIR_Msk, rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR_Msk},
+		{"pio0_SM0_EXECCTRL_SIDE_PINDIR_Pos", pio0_SM0_EXECCTRL_SIDE_PINDIR_Pos, rp.PIO0_SM0_EXECCTRL_SIDE_PINDIR_Pos},
+		{"pio0_SM0_EXECCTRL_STATUS_N_Msk", pio0_SM0_EXECCTRL_STATUS_N_Msk, rp.PIO0_SM0_EXECCTRL_STATUS_N_Msk},
+		{"pio0_SM0_EXECCTRL_STATUS_N_Pos", pio0_SM0_EXECCTRL_STATUS_N_Pos, rp.PIO0_SM0_EXECCTRL_STATUS_N_Pos},
+		{"pio0_SM0_EXECCTRL_STATUS_SEL_Msk", pio0_SM0_EXECCTRL_STATUS_SEL_Msk, rp.PIO0_SM0_EXECCTRL_STATUS_SEL_Msk},
+		{"pio0_SM0_EXECCTRL_STATUS_SEL_Pos", pio0_SM0_EXECCTRL_STATUS_SEL_Pos, rp.PIO0_SM0_EXECCTRL_STATUS_SEL_Pos},
+		{"pio0_SM0_EXECCTRL_WRAP_BOTTOM_Msk", pio0_SM0_EXECCTRL_WRAP_BOTTOM_Msk, rp.PIO0_SM0_EXECCTRL_WRAP_BOTTOM_Msk},
+		{"pio0_SM0_EXECCTRL_WRAP_BOTTOM_Pos", pio0_SM0_EXECCTRL_WRAP_BOTTOM_Pos, rp.PIO0_SM0_EXECCTRL_WRAP_BOTTOM_Pos},
+		{"pio0_SM0_EXECCTRL_WRAP_TOP_Msk", pio0_SM0_EXECCTRL_WRAP_TOP_Msk, rp.PIO0_SM0_EXECCTRL_WRAP_TOP_Msk},
+		{"pio0_SM0_EXECCTRL_WRAP_TOP_Pos", pio0_SM0_EXECCTRL_WRAP_TOP_Pos, rp.PIO0_SM0_EXECCTRL_WRAP_TOP_Pos},
+		{"pio0_SM0_PINCTRL_IN_BASE_Msk", pio0_SM0_PINCTRL_IN_BASE_Msk, rp.PIO0_SM0_PINCTRL_IN_BASE_Msk},
+		{"pio0_SM0_PINCTRL_IN_BASE_Pos", pio0_SM0_PINCTRL_IN_BASE_Pos, rp.PIO0_SM0_PINCTRL_IN_BASE_Pos},
+		{"pio0_SM0_PINCTRL_OUT_BASE_Msk", pio0_SM0_PINCTRL_OUT_BASE_Msk, rp.PIO0_SM0_PINCTRL_OUT_BASE_Msk},
+		{"pio0_SM0_PINCTRL_OUT_BASE_Pos", pio0_SM0_PINCTRL_OUT_BASE_Pos, rp.PIO0_SM0_PINCTRL_OUT_BASE_Pos},
+		{"pio0_SM0_PINCTRL_OUT_COUNT_Msk", pio0_SM0_PINCTRL_OUT_COUNT_Msk, rp.PIO0_SM0_PINCTRL_OUT_COUNT_Msk},
+		{"pio0_SM0_PINCTRL_OUT_COUNT_Pos", pio0_SM0_PINCTRL_OUT_COUNT_Pos, rp.PIO0_SM0_PINCTRL_OUT_COUNT_Pos},
+		{"pio0_SM0_PINCTRL_SET_BASE_Msk", pio0_SM0_PINCTRL_SET_BASE_Msk, rp.PIO0_SM0_PINCTRL_SET_BASE_Msk},
+		{"pio0_SM0_PINCTRL_SET_BASE_Pos", pio0_SM0_PINCTRL_SET_BASE_Pos, rp.PIO0_SM0_PINCTRL_SET_BASE_Pos},
+		{"pio0_SM0_PINCTRL_SET_COUNT_Msk", pio0_SM0_PINCTRL_SET_COUNT_Msk, rp.PIO0_SM0_PINCTRL_SET_COUNT_Msk},
+		{"pio0_SM0_PINCTRL_SET_COUNT_Pos", pio0_SM0_PINCTRL_SET_COUNT_Pos, rp.PIO0_SM0_PINCTRL_SET_COUNT_Pos},
+		{"pio0_SM0_PINCTRL_SIDESET_BASE_Msk", pio0_SM0_PINCTRL_SIDESET_BASE_Msk, rp.PIO0_SM0_PINCTRL_SIDESET_BASE_Msk},
+		{"pio0_SM0_PINCTRL_SIDESET_BASE_Pos", pio0_SM0_PINCTRL_SIDESET_BASE_Pos, rp.PIO0_SM0_PINCTRL_SIDESET_BASE_Pos},
+		{"pio0_SM0_PINCTRL_SIDESET_COUNT_Msk", pio0_SM0_PINCTRL_SIDESET_COUNT_Msk, rp.PIO0_SM0_PINCTRL_SIDESET_COUNT_Msk},
+		{"pio0_SM0_PINCTRL_SIDESET_COUNT_Pos", pio0_SM0_PINCTRL_SIDESET_COUNT_Pos, rp.PIO0_SM0_PINCTRL_SIDESET_COUNT_Pos},
+		{"pio0_SM0_SHIFTCTRL_AUTOPULL", pio0_SM0_SHIFTCTRL_AUTOPULL, rp.PIO0_SM0_SHIFTCTRL_AUTOPULL},
+		{"pio0_SM0_SHIFTCTRL_AUTOPULL_Msk", pio0_SM0_SHIFTCTRL_AUTOPULL_Msk, rp.PIO0_SM0_SHIFTCTRL_AUTOPULL_Msk},
+		{"pio0_SM0_SHIFTCTRL_AUTOPULL_Pos", pio0_SM0_SHIFTCTRL_AUTOPULL_Pos, rp.PIO0_SM0_SHIFTCTRL_AUTOPULL_Pos},
+		{"pio0_SM0_SHIFTCTRL_AUTOPUSH", pio0_SM0_SHIFTCTRL_AUTOPUSH, rp.PIO0_SM0_SHIFTCTRL_AUTOPUSH},
+		{"pio0_SM0_SHIFTCTRL_AUTOPUSH_Msk", pio0_SM0_SHIFTCTRL_AUTOPUSH_Msk, rp.PIO0_SM0_SHIFTCTRL_AUTOPUSH_Msk},
+		{"pio0_SM0_SHIFTCTRL_AUTOPUSH_Pos", pio0_SM0_SHIFTCTRL_AUTOPUSH_Pos, rp.PIO0_SM0_SHIFTCTRL_AUTOPUSH_Pos},
+		{"pio0_SM0_SHIFTCTRL_FJOIN_RX_Msk", pio0_SM0_SHIFTCTRL_FJOIN_RX_Msk, rp.PIO0_SM0_SHIFTCTRL_FJOIN_RX_Msk},
+		{"pio0_SM0_SHIFTCTRL_FJOIN_TX_Msk", pio0_SM0_SHIFTCTRL_FJOIN_TX_Msk, rp.PIO0_SM0_SHIFTCTRL_FJOIN_TX_Msk},
+		{"pio0_SM0_SHIFTCTRL_FJOIN_TX_Pos", pio0_SM0_SHIFTCTRL_FJOIN_TX_Pos, rp.PIO0_SM0_SHIFTCTRL_FJOIN_TX_Pos},
+		{"pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk", pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk, rp.PIO0_SM0_SHIFTCTRL_IN_SHIFTDIR_Msk},
+		{"pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos", pio0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos, rp.PIO0_SM0_SHIFTCTRL_IN_SHIFTDIR_Pos},
+		{"pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk", pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk, rp.PIO0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Msk},
+		{"pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos", pio0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos, rp.PIO0_SM0_SHIFTCTRL_OUT_SHIFTDIR_Pos},
+		{"pio0_SM0_SHIFTCTRL_PULL_THRESH_Msk", pio0_SM0_SHIFTCTRL_PULL_THRESH_Msk, rp.PIO0_SM0_SHIFTCTRL_PULL_THRESH_Msk},
+		{"pio0_SM0_SHIFTCTRL_PULL_THRESH_Pos", pio0_SM0_SHIFTCTRL_PULL_THRESH_Pos, rp.PIO0_SM0_SHIFTCTRL_PULL_THRESH_Pos},
+		{"pio0_SM0_SHIFTCTRL_PUSH_THRESH_Msk", pio0_SM0_SHIFTCTRL_PUSH_THRESH_Msk, rp.PIO0_SM0_SHIFTCTRL_PUSH_THRESH_Msk},
+		{"pio0_SM0_SHIFTCTRL_PUSH_THRESH_Pos", pio0_SM0_SHIFTCTRL_PUSH_THRESH_Pos, rp.PIO0_SM0_SHIFTCTRL_PUSH_THRESH_Pos},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %#x, device/rp has %#x", tt.name, tt.got, tt.want)
+		}
+	}
+}
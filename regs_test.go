@@ -0,0 +1,94 @@
+package pio
+
+import "testing"
+
+func TestCtrlEnabledMask(t *testing.T) {
+	// Restart bits and reserved bits outside the enable nibble must survive.
+	const other = 0xfffffff0
+	for mask := uint32(0); mask < 16; mask++ {
+		ctrl := ctrlWithEnabledMask(other|^mask&0xf, mask)
+		if got := ctrlEnabledMask(ctrl); got != mask {
+			t.Errorf("EnabledMask after SetEnabledMask(%#b) = %#b", mask, got)
+		}
+		if ctrl&^0xf != other {
+			t.Errorf("SetEnabledMask(%#b) changed CTRL bits %#x", mask, (ctrl^other)&^0xf)
+		}
+		for sm := uint8(0); sm < 4; sm++ {
+			if want := mask&(1<<sm) != 0; ctrlEnabled(ctrl, sm) != want {
+				t.Errorf("Enabled(%d) with mask %#b = %v, want %v", sm, mask, !want, want)
+			}
+		}
+	}
+	// Bits above the nibble do not leak into other CTRL fields.
+	if got := ctrlWithEnabledMask(0, 0xf5); got != 0x5 {
+		t.Errorf("SetEnabledMask(0xf5) wrote CTRL %#x, want 0x5", got)
+	}
+}
+
+func TestFdebugTxOverflowed(t *testing.T) {
+	const (
+		rxStall  = 0x1
+		rxUnder  = 0x100
+		txOver   = 0x10000
+		txStall  = 0x1000000
+		stalledA = 2 // State machine whose TX FIFO overflowed.
+	)
+	// A stalled state machine with a full TX FIFO drops further writes: the
+	// hardware sets its TXOVER and TXSTALL flags.
+	fdebug := uint32(txOver|txStall|rxUnder) << stalledA
+	fdebug |= rxStall << 1
+	for sm := uint8(0); sm < 4; sm++ {
+		overflowed, clear := fdebugTxOverflowed(fdebug, sm)
+		if overflowed != (sm == stalledA) {
+			t.Errorf("LastTxOverflowed on state machine %d = %v", sm, overflowed)
+		}
+		if clear != txOver<<sm {
+			t.Errorf("LastTxOverflowed on state machine %d clears %#x, want only its TXOVER bit %#x", sm, clear, txOver<<sm)
+		}
+	}
+	if overflowed, _ := fdebugTxOverflowed(fdebug&^(txOver<<stalledA), stalledA); overflowed {
+		t.Error("overflow reported after TXOVER was cleared")
+	}
+}
+
+func TestInputSyncBypassBit(t *testing.T) {
+	var seen uint32
+	for pin := uint8(0); pin < 32; pin++ {
+		bit := inputSyncBypassBit(pin)
+		if bit != 1<<pin {
+			t.Errorf("INPUT_SYNC_BYPASS bit of GPIO %d = %#x, want %#x", pin, bit, uint32(1)<<pin)
+		}
+		seen |= bit
+	}
+	if seen != 0xffffffff {
+		t.Errorf("INPUT_SYNC_BYPASS bits cover %#x", seen)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("no panic for pin 32")
+		}
+	}()
+	inputSyncBypassBit(32)
+}
+
+func TestFIFOFlushKeepsJoin(t *testing.T) {
+	for _, join := range []FifoJoin{FIFO_JOIN_NONE, FIFO_JOIN_TX, FIFO_JOIN_RX} {
+		cfg := DefaultStateMachineConfig()
+		cfg.SetFIFOJoin(join)
+		cfg.SetOutShift(true, true, 8)
+		shiftctrl := cfg.ShiftCtrl
+		flushed := false
+		for _, toggle := range fifoFlushToggles {
+			// The XOR alias flips the written bits.
+			before := shiftctrl
+			shiftctrl ^= toggle
+			flushed = flushed || (before^shiftctrl)&(pio0_SM0_SHIFTCTRL_FJOIN_RX_Msk|pio0_SM0_SHIFTCTRL_FJOIN_TX_Msk) != 0
+		}
+		if !flushed {
+			t.Errorf("join %d: FIFO join bits never changed, FIFOs not flushed", join)
+		}
+		if shiftctrl != cfg.ShiftCtrl {
+			t.Errorf("join %d: SHIFTCTRL %#x after ClearFIFOs, want %#x", join, shiftctrl, cfg.ShiftCtrl)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import "time"
+
+// runOnceQuiet is how long RunOnce keeps polling after the FIFOs stop moving.
+const runOnceQuiet = time.Millisecond
+
+// RunOnce runs a data transforming program, such as a CRC computation, in a
+// request/response fashion for testing and bring-up. The state machine must be
+// initialized and disabled. RunOnce enables it, feeds inputs into the TX FIFO
+// while collecting every word pushed to the RX FIFO, then disables it and
+// returns the collected words.
+//
+// There is no way to tell when a program is done, so RunOnce stops once the
+// FIFOs have been quiet for about a millisecond: no input taken from the TX FIFO
+// and no output pushed to the RX FIFO. Programs that pause longer than that
+// between outputs, e.g. with a slow clock divider, are cut short. If inputs
+// remain unconsumed when it stops the output so far is returned with ErrInputNotConsumed.
+func (sm StateMachine) RunOnce(inputs []uint32) ([]uint32, error) {
+	var outputs []uint32
+	sm.SetEnabled(true)
+	txLevel := sm.TxFIFOLevel()
+	lastActivity := time.Now()
+	for {
+		active := false
+		if len(inputs) > 0 && !sm.IsTxFIFOFull() {
+			sm.TxPut(inputs[0])
+			inputs = inputs[1:]
+			active = true
+		}
+		if !sm.IsRxFIFOEmpty() {
+			outputs = append(outputs, sm.RxGet())
+			active = true
+		}
+		if level := sm.TxFIFOLevel(); level != txLevel {
+			txLevel = level
+			active = true
+		}
+		now := time.Now()
+		if active {
+			lastActivity = now
+		} else if now.Sub(lastActivity) > runOnceQuiet {
+			break
+		}
+	}
+	sm.SetEnabled(false)
+	if len(inputs) > 0 || !sm.IsTxFIFOEmpty() {
+		return outputs, ErrInputNotConsumed
+	}
+	return outputs, nil
+}
@@ -0,0 +1,63 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import "runtime"
+
+// RxChan delivers words read from a state machine's RX FIFO on channel C.
+// It is created with StateMachine.RxChannel.
+type RxChan struct {
+	// C receives the words read from the RX FIFO. It is closed after Close.
+	C    <-chan uint32
+	done chan struct{}
+}
+
+// RxChannel starts a goroutine that polls the state machine's RX FIFO and
+// delivers each word on the returned RxChan's channel, which has buffer capacity buf.
+// This lets PIO input be consumed with range like any Go stream:
+//
+//	rx := sm.RxChannel(16)
+//	defer rx.Close()
+//	for word := range rx.C {
+//		...
+//	}
+//
+// The goroutine yields to the scheduler while the FIFO is empty, so it keeps the
+// CPU busy polling whenever no other goroutine is runnable. If words arrive faster
+// than they are received the channel buffer and then the RX FIFO fill up and the
+// state machine stalls on push, or drops data with a non-blocking push.
+// Use interrupts or DMA for high data rates.
+func (sm StateMachine) RxChannel(buf int) *RxChan {
+	c := make(chan uint32, buf)
+	rx := &RxChan{C: c, done: make(chan struct{})}
+	go rx.run(sm, c)
+	return rx
+}
+
+func (rx *RxChan) run(sm StateMachine, c chan<- uint32) {
+	defer close(c)
+	for {
+		select {
+		case <-rx.done:
+			return
+		default:
+		}
+		if sm.IsRxFIFOEmpty() {
+			runtime.Gosched()
+			continue
+		}
+		select {
+		case c <- sm.RxGet():
+		case <-rx.done:
+			return
+		}
+	}
+}
+
+// Close stops the goroutine, after which C is closed. Words still in the RX
+// FIFO are left there but a word already read and waiting to be delivered is
+// dropped. Close must be called only once.
+func (rx *RxChan) Close() {
+	close(rx.done)
+}
@@ -0,0 +1,72 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import "runtime/interrupt"
+
+// Streamer feeds a state machine's TX FIFO from a buffer using the TX FIFO
+// not full interrupt, so the CPU only wakes up when there is room in the FIFO.
+// It is a low power alternative to busy-polling and an alternative to DMA
+// when all DMA channels are in use.
+//
+// The Streamer does not install an interrupt handler. The user must install
+// one for the PIO block and line in use and call HandleInterrupt from it:
+//
+//	var streamer = pio.NewStreamer(pio.PIO0.StateMachine(0), 0)
+//
+//	func init() {
+//		interrupt.New(rp.IRQ_PIO0_IRQ_0, func(interrupt.Interrupt) {
+//			streamer.HandleInterrupt()
+//		}).Enable()
+//	}
+type Streamer struct {
+	sm      StateMachine
+	irqLine uint8
+	// pending holds the words yet to be written to the TX FIFO.
+	pending []uint32
+}
+
+// NewStreamer returns a Streamer for sm using PIO system interrupt line irqLine (0 or 1).
+func NewStreamer(sm StateMachine, irqLine uint8) *Streamer {
+	if irqLine > 1 {
+		panic("invalid PIO IRQ line")
+	}
+	return &Streamer{sm: sm, irqLine: irqLine}
+}
+
+// Start begins streaming data to the TX FIFO and returns immediately. data
+// must not be modified until Busy returns false. Starting while a previous
+// stream is in progress replaces the pending data.
+func (s *Streamer) Start(data []uint32) {
+	state := interrupt.Disable()
+	s.pending = data
+	s.fill()
+	interrupt.Restore(state)
+}
+
+// Busy returns true while there is data waiting to be written to the TX FIFO.
+// Data already in the FIFO may not have been shifted out yet.
+func (s *Streamer) Busy() bool {
+	state := interrupt.Disable()
+	busy := len(s.pending) > 0
+	interrupt.Restore(state)
+	return busy
+}
+
+// HandleInterrupt refills the TX FIFO. It must be called from the interrupt
+// handler of the Streamer's PIO interrupt line.
+func (s *Streamer) HandleInterrupt() {
+	s.fill()
+}
+
+// fill writes pending data to the TX FIFO until it is full and enables the
+// TX not full interrupt only while data remains, since the source stays
+// asserted as long as the FIFO has room.
+func (s *Streamer) fill() {
+	for len(s.pending) > 0 && !s.sm.IsTxFIFOFull() {
+		s.sm.TxPut(s.pending[0])
+		s.pending = s.pending[1:]
+	}
+	s.sm.SetInterruptEnabled(s.irqLine, InterruptTxNotFull, len(s.pending) > 0)
+}
@@ -0,0 +1,34 @@
+package pio
+
+import "time"
+
+// CyclesForDuration returns the number of state machine cycles, rounded to the
+// nearest cycle, that elapse during d for a state machine clocked at sysClkHz
+// divided by div. Use it to compute delay counts or X/Y loop preloads.
+//
+// Keep in mind a loop's cycle count includes the cycles of every instruction
+// in it, e.g. a `jmp x--` loop takes one cycle per iteration plus its delay.
+func CyclesForDuration(sysClkHz uint32, div float32, d time.Duration) uint32 {
+	cycles := float64(d) * float64(sysClkHz) / (float64(div) * float64(time.Second))
+	return uint32(cycles + 0.5)
+}
+
+// DurationForCycles returns the duration of cycles state machine cycles for a
+// state machine clocked at sysClkHz divided by div. It is the inverse of CyclesForDuration.
+func DurationForCycles(sysClkHz uint32, div float32, cycles uint32) time.Duration {
+	d := float64(cycles) * float64(div) * float64(time.Second) / float64(sysClkHz)
+	return time.Duration(d + 0.5)
+}
+
+// MaxStateMachineFrequency returns the fastest a state machine can run for a
+// system clock of sysClkHz: a clock divider of 1, executing one instruction per
+// system clock cycle. Requesting a faster rate from a divider calculator such as
+// SetBaud just clamps the divider to 1.
+//
+// The data rate of a program is this frequency divided by the cycles it spends
+// on each bit, counting delays. A program outputting one bit per instruction
+// moves a bit per cycle on each pin it drives, whereas one that takes two cycles
+// per bit, e.g. an 'out' followed by a side-set clock edge, halves the data rate.
+func MaxStateMachineFrequency(sysClkHz uint32) uint32 {
+	return sysClkHz
+}
@@ -0,0 +1,86 @@
+package pio
+
+import "strconv"
+
+// VerifyConfigForProgram cross-checks a state machine configuration against
+// the program it will run and returns a description of every mismatch found,
+// or nil if none. Such mismatches typically leave a state machine silently dead
+// or producing garbage. It checks that:
+//
+//   - the side-set count, optional and pindirs settings match the program's metadata.
+//   - the wrap spans as many instructions as the program's wrap.
+//   - with autopull, every 'out' bit count divides the pull threshold, and
+//     without it, the program has a 'pull' if it uses 'out'. Likewise for
+//     autopush, 'in' and 'push'.
+func VerifyConfigForProgram(cfg *StateMachineConfig, p *Program) []string {
+	var problems []string
+	report := func(msg string) { problems = append(problems, msg) }
+	itoa := strconv.Itoa
+
+	sideSetCount := int((cfg.PinCtrl & pio0_SM0_PINCTRL_SIDESET_COUNT_Msk) >> pio0_SM0_PINCTRL_SIDESET_COUNT_Pos)
+	sideSetOpt := cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_EN != 0
+	wantCount := int(p.SideSetCount)
+	if p.SideSetOpt {
+		wantCount++
+	}
+	if sideSetCount != wantCount {
+		report("side-set count is " + itoa(sideSetCount) + ", program needs " + itoa(wantCount) + " including the enable bit")
+	}
+	if sideSetOpt != p.SideSetOpt {
+		report("side-set optional setting does not match program")
+	}
+	if (cfg.ExecCtrl&pio0_SM0_EXECCTRL_SIDE_PINDIR != 0) != p.SideSetPindirs {
+		report("side-set pindirs setting does not match program")
+	}
+
+	wrapTop := int((cfg.ExecCtrl & pio0_SM0_EXECCTRL_WRAP_TOP_Msk) >> pio0_SM0_EXECCTRL_WRAP_TOP_Pos)
+	wrapBottom := int((cfg.ExecCtrl & pio0_SM0_EXECCTRL_WRAP_BOTTOM_Msk) >> pio0_SM0_EXECCTRL_WRAP_BOTTOM_Pos)
+	if wrapTop-wrapBottom != int(p.Wrap)-int(p.WrapTarget) {
+		report("wrap spans " + itoa(wrapTop-wrapBottom+1) + " instructions, program wrap spans " +
+			itoa(int(p.Wrap)-int(p.WrapTarget)+1))
+	}
+
+	var usesOut, usesPull, usesIn, usesPush bool
+	autopull := cfg.ShiftCtrl&pio0_SM0_SHIFTCTRL_AUTOPULL != 0
+	autopush := cfg.ShiftCtrl&pio0_SM0_SHIFTCTRL_AUTOPUSH != 0
+	pullThresh := thresholdBits(cfg.ShiftCtrl, pio0_SM0_SHIFTCTRL_PULL_THRESH_Msk, pio0_SM0_SHIFTCTRL_PULL_THRESH_Pos)
+	pushThresh := thresholdBits(cfg.ShiftCtrl, pio0_SM0_SHIFTCTRL_PUSH_THRESH_Msk, pio0_SM0_SHIFTCTRL_PUSH_THRESH_Pos)
+	for i, instr := range p.Instructions {
+		bits := int(instr & 0x1f)
+		if bits == 0 {
+			bits = 32
+		}
+		switch DecodeInstruction(instr, p.SideSetCount, p.SideSetOpt).Major {
+		case INSTR_BITS_OUT:
+			usesOut = true
+			if autopull && pullThresh%bits != 0 {
+				report("instruction " + itoa(i) + ": out of " + itoa(bits) + " bits does not divide pull threshold " + itoa(pullThresh))
+			}
+		case INSTR_BITS_IN:
+			usesIn = true
+			if autopush && pushThresh%bits != 0 {
+				report("instruction " + itoa(i) + ": in of " + itoa(bits) + " bits does not divide push threshold " + itoa(pushThresh))
+			}
+		case INSTR_BITS_PULL:
+			usesPull = true
+		case INSTR_BITS_PUSH:
+			usesPush = true
+		}
+	}
+	if usesOut && !autopull && !usesPull {
+		report("program uses out but has no pull and autopull is disabled")
+	}
+	if usesIn && !autopush && !usesPush {
+		report("program uses in but has no push and autopush is disabled")
+	}
+	return problems
+}
+
+// thresholdBits decodes a SHIFTCTRL push or pull threshold, where 0 means 32.
+func thresholdBits(shiftctrl, msk, pos uint32) int {
+	bits := int((shiftctrl & msk) >> pos)
+	if bits == 0 {
+		return 32
+	}
+	return bits
+}
@@ -0,0 +1,38 @@
+package pio
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// ctxPollInterval is the number of busy-wait iterations between checks of
+// a context's cancellation. Checking on every iteration would slow down tight loops.
+const ctxPollInterval = 64
+
+// waitCtx busy-waits while cond returns true, checking ctx every ctxPollInterval
+// iterations. The context is checked before waiting so an already cancelled
+// context always returns an error.
+//
+// On TinyGo's cooperative scheduler the timer goroutine that expires a deadline
+// context never runs while this loop spins, so the deadline is compared with
+// the clock directly, returning context.DeadlineExceeded. Each check also yields
+// so that another goroutine can cancel ctx.
+func waitCtx(ctx context.Context, cond func() bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	deadline, hasDeadline := ctx.Deadline()
+	for i := 1; cond(); i++ {
+		if i%ctxPollInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if hasDeadline && !time.Now().Before(deadline) {
+				return context.DeadlineExceeded
+			}
+			runtime.Gosched()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package pio
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitCtxDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := waitCtx(ctx, func() bool { return true }) // Never satisfied.
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("wait took %v past a 10ms deadline", elapsed)
+	}
+}
+
+func TestWaitCtxCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+	}()
+	if err := waitCtx(ctx, func() bool { return true }); err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWaitCtxDone(t *testing.T) {
+	n := 0
+	err := waitCtx(context.Background(), func() bool { n++; return n < 1000 })
+	if err != nil || n != 1000 {
+		t.Fatalf("got %v after %d polls, want nil after 1000", err, n)
+	}
+}
+
+func TestWaitCtxAlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	polls := 0
+	err := waitCtx(ctx, func() bool { polls++; return true }) // Never satisfied.
+	if err != context.Canceled {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	if polls != 0 {
+		t.Errorf("polled %d times with a cancelled context, want 0", polls)
+	}
+}
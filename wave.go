@@ -0,0 +1,77 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import "machine"
+
+// GenerateSquareWave builds a program that outputs a 50% duty cycle square
+// wave of freqHz on a single side-set pin, along with a configuration whose
+// clock divider makes the output match freqHz as closely as possible.
+// SquareWaveFrequency reports the frequency actually achieved.
+//
+// The program takes 2 cycles per period so the achievable range is
+// sysclk/2/65536 to sysclk/2, about 954Hz to 62.5MHz at 125MHz. Frequencies that
+// do not evenly divide sysclk/2 use a fractional divider and have some jitter.
+//
+// The caller must load the program, set the side-set pin with
+// cfg.SetSidePins and prepare the pin with InitSideSetPins:
+//
+//	prog, cfg, err := pio.GenerateSquareWave(1000000)
+//	offset, err := sm.PIO.AddProgram(prog.Instructions, prog.Origin)
+//	err = sm.InitSideSetPins(pin, 1)
+//	cfg.SetSidePins(pin)
+//	sm.Init(offset, cfg)
+//	sm.SetEnabled(true)
+func GenerateSquareWave(freqHz uint32) (*Program, StateMachineConfig, error) {
+	div, err := squareWaveDivider(freqHz)
+	if err != nil {
+		return nil, StateMachineConfig{}, err
+	}
+	// .side_set 1
+	//     nop side 1
+	//     jmp 0 side 0
+	// The jump is relocated on load so the program does not depend on wrap.
+	prog := &Program{
+		Instructions: []uint16{
+			EncodeNOP() | EncodeSideSet(1, 1),
+			EncodeJmp(0) | EncodeSideSet(1, 0),
+		},
+		Origin:       -1,
+		Wrap:         1,
+		SideSetCount: 1,
+	}
+	// The default wrap spans all of instruction memory, which is harmless
+	// since the program jumps back to its start.
+	cfg := DefaultStateMachineConfig()
+	cfg.SetSideSet(1, false, false)
+	cfg.SetClkDivIntFrac(uint16(div>>8), uint8(div))
+	return prog, cfg, nil
+}
+
+// SquareWaveFrequency returns the frequency of the square wave that
+// GenerateSquareWave produces for freqHz at the current system clock, which
+// differs from freqHz by the resolution of the clock divider.
+func SquareWaveFrequency(freqHz uint32) (uint32, error) {
+	div, err := squareWaveDivider(freqHz)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(uint64(machine.CPUFrequency()) * 256 / (div * squareWaveCycles)), nil
+}
+
+// squareWaveCycles is the period of the GenerateSquareWave program in cycles.
+const squareWaveCycles = 2
+
+// squareWaveDivider returns the 16.8 fixed point clock divider for a square
+// wave of freqHz, or ErrFrequencyOutOfRange if the divider does not fit.
+func squareWaveDivider(freqHz uint32) (uint64, error) {
+	if freqHz == 0 {
+		return 0, ErrFrequencyOutOfRange
+	}
+	div := uint64(machine.CPUFrequency()) * 256 / (uint64(freqHz) * squareWaveCycles)
+	if div < 256 || div > 0xffffff {
+		return 0, ErrFrequencyOutOfRange
+	}
+	return div, nil
+}
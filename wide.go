@@ -0,0 +1,135 @@
+//go:build rp2040
+// +build rp2040
+
+package pio
+
+import (
+	"device/rp"
+	"machine"
+)
+
+// WideOutput drives a parallel bus wider than a single state machine can feed
+// by splitting it into lanes, one per state machine, running the same program in
+// lockstep. It is created with PIO.ConfigureWideOutput.
+type WideOutput struct {
+	pio    *PIO
+	sms    [4]StateMachine
+	lanes  uint8
+	mask   uint32
+	offset uint8
+}
+
+// ConfigureWideOutput claims lanes (2 or 4) state machines of the block, loads
+// program once and initializes each state machine with cfg to drive its own slice
+// of pinsPerLane consecutive 'out' pins, lane i starting at base+i*pinsPerLane.
+// The TX FIFOs are joined to 8 entries per lane and the pins are routed to the
+// block as outputs. The lanes are left disabled; call Start to run them.
+//
+// The program would typically be a single 'out pins, n' with autopull, so that
+// 2 lanes of 8 pins push 16 data bits per cycle and 4 lanes of 8 pins 32 bits.
+// The wrap of cfg is overwritten with the program's relocated wrap.
+//
+// Start enables the lanes and restarts their clock dividers in one register
+// write so they run in phase. They only stay aligned while every lane is fed on
+// time: a lane whose FIFO runs dry stalls alone and falls out of step with the
+// rest. Write keeps the FIFOs balanced by distributing data round-robin.
+func (pio *PIO) ConfigureWideOutput(program *Program, cfg StateMachineConfig, base machine.Pin, pinsPerLane, lanes uint8) (*WideOutput, error) {
+	if lanes != 2 && lanes != 4 {
+		return nil, ErrInvalidLaneCount
+	}
+	if pinsPerLane > 32 {
+		// Out pin count is at most 32; also keeps pinsPerLane*lanes from wrapping.
+		return nil, ErrPinOutOfRange
+	}
+	if err := (PinRange{Base: base, Count: pinsPerLane * lanes}).Validate(); err != nil {
+		return nil, err
+	}
+	w := &WideOutput{pio: pio, lanes: lanes}
+	if !w.claim() {
+		return nil, ErrNoFreeStateMachine
+	}
+	offset, err := pio.AddProgram(program.Instructions, program.Origin)
+	if err != nil {
+		w.unclaim()
+		return nil, err
+	}
+	w.offset = offset
+	cfg.SetWrap(offset+program.WrapTarget, offset+program.Wrap)
+	cfg.SetFIFOJoin(FIFO_JOIN_TX)
+	for i, sm := range w.sms[:lanes] {
+		laneCfg := cfg
+		laneCfg.SetOutPins(base+machine.Pin(uint8(i)*pinsPerLane), pinsPerLane)
+		sm.Init(offset, laneCfg)
+		sm.initConfigPins(&laneCfg)
+	}
+	return w, nil
+}
+
+// claim claims w.lanes unclaimed state machines at once, or none.
+func (w *WideOutput) claim() bool {
+	w.pio.lock()
+	defer w.pio.unlock()
+	n := uint8(0)
+	for i := uint8(0); i < 4 && n < w.lanes; i++ {
+		if w.pio.claimedMask&(1<<i) == 0 {
+			w.sms[n] = w.pio.StateMachine(i)
+			w.mask |= 1 << i
+			n++
+		}
+	}
+	if n < w.lanes {
+		w.mask = 0
+		return false
+	}
+	w.pio.claimedMask |= uint8(w.mask)
+	return true
+}
+
+func (w *WideOutput) unclaim() {
+	w.pio.lock()
+	w.pio.claimedMask &^= uint8(w.mask)
+	w.pio.unlock()
+}
+
+// Lane returns the state machine driving lane i, e.g. to feed it with DMA
+// through its TxDREQ. It returns ErrInvalidLane if i is not below the number
+// of lanes.
+func (w *WideOutput) Lane(i uint8) (StateMachine, error) {
+	if i >= w.lanes {
+		return StateMachine{}, ErrInvalidLane
+	}
+	return w.sms[i], nil
+}
+
+// Start enables all lanes on the same cycle with their clock dividers in phase.
+func (w *WideOutput) Start() {
+	AliasRegister(&w.pio.HW.CTRL, AliasSet).Set(w.mask<<rp.PIO0_CTRL_SM_ENABLE_Pos |
+		w.mask<<rp.PIO0_CTRL_CLKDIV_RESTART_Pos)
+}
+
+// Stop disables all lanes on the same cycle.
+func (w *WideOutput) Stop() {
+	AliasRegister(&w.pio.HW.CTRL, AliasClear).Set(w.mask << rp.PIO0_CTRL_SM_ENABLE_Pos)
+}
+
+// Write distributes data round-robin over the lanes, data[j] going to lane
+// j%lanes, blocking while a FIFO is full. len(data) must be a multiple of the
+// number of lanes so that every lane receives the same amount of data,
+// otherwise ErrUnbalancedWrite is returned and nothing is written.
+func (w *WideOutput) Write(data []uint32) error {
+	if len(data)%int(w.lanes) != 0 {
+		return ErrUnbalancedWrite
+	}
+	for j, word := range data {
+		w.sms[j%int(w.lanes)].TxPutBlocking(word)
+	}
+	return nil
+}
+
+// Close stops the lanes, frees the program space and releases the state machines.
+func (w *WideOutput) Close() error {
+	w.Stop()
+	err := w.pio.RemoveProgram(w.offset)
+	w.unclaim()
+	return err
+}